@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/hub"
+)
+
+// webSocketHub returns the WebSocket connection limits. Each one can be
+// overridden from the environment; unset values use hub.DefaultConfig:
+// WS_MAX_MESSAGE_BYTES, WS_RATE_LIMIT (messages per second, -1 for unlimited),
+// WS_RATE_BURST, WS_PONG_WAIT and WS_WRITE_WAIT (durations like "30s"),
+// and WS_COMPRESSION (true/false). Zero limits are refused, since the hub
+// would take them as unset.
+func webSocketHub() hub.Config {
+	cfg := hub.DefaultConfig()
+	parseEnv("WS_MAX_MESSAGE_BYTES", func(v string) (err error) {
+		cfg.MaxMessageSize, err = strconv.ParseInt(v, 10, 64)
+		return err
+	})
+	parseEnv("WS_RATE_LIMIT", func(v string) (err error) {
+		if cfg.RateLimit, err = strconv.ParseFloat(v, 64); err == nil && cfg.RateLimit == 0 {
+			err = errors.New("use a positive rate, or -1 for unlimited")
+		}
+		return err
+	})
+	parseEnv("WS_RATE_BURST", func(v string) (err error) {
+		if cfg.RateBurst, err = strconv.Atoi(v); err == nil && cfg.RateBurst <= 0 {
+			err = errors.New("must be positive")
+		}
+		return err
+	})
+	parseEnv("WS_PONG_WAIT", func(v string) (err error) {
+		cfg.PongWait, err = time.ParseDuration(v)
+		cfg.PingPeriod = cfg.PongWait * 9 / 10
+		return err
+	})
+	parseEnv("WS_WRITE_WAIT", func(v string) (err error) {
+		cfg.WriteWait, err = time.ParseDuration(v)
+		return err
+	})
+	parseEnv("WS_COMPRESSION", func(v string) (err error) {
+		cfg.Compression, err = strconv.ParseBool(v)
+		return err
+	})
+	return cfg
+}
+
+// chatRetention returns how long WebSocket room messages are kept:
+// CHAT_RETENTION (a duration, default 720h; 0 keeps messages regardless of
+// age), CHAT_MAX_PER_ROOM (default 1000; 0 for no limit) and
+// CHAT_CLEANUP_INTERVAL (how often old messages are deleted, default 1h).
+func chatRetention() data.MessageRetention {
+	r := data.MessageRetention{MaxAge: 30 * 24 * time.Hour, MaxPerRoom: 1000, Interval: time.Hour}
+	parseEnv("CHAT_RETENTION", func(v string) (err error) {
+		r.MaxAge, err = time.ParseDuration(v)
+		return err
+	})
+	parseEnv("CHAT_MAX_PER_ROOM", func(v string) (err error) {
+		r.MaxPerRoom, err = strconv.Atoi(v)
+		return err
+	})
+	parseEnv("CHAT_CLEANUP_INTERVAL", func(v string) (err error) {
+		r.Interval, err = time.ParseDuration(v)
+		return err
+	})
+	return r
+}
+
+// parseEnv passes the environment variable name to parse if it's set,
+// exiting if its value is invalid.
+func parseEnv(name string, parse func(string) error) {
+	if value := os.Getenv(name); value != "" {
+		if err := parse(value); err != nil {
+			log.Fatalf("invalid %s %q: %v", name, value, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"github.com/shahinzaman102/Go_JumpStart/internal/db"
 	"github.com/shahinzaman102/Go_JumpStart/internal/handlers"
 	"github.com/shahinzaman102/Go_JumpStart/internal/routes"
+	"github.com/shahinzaman102/Go_JumpStart/internal/wiki"
 
 	_ "net/http/pprof"
 
@@ -52,6 +54,8 @@ func main() {
 
 	authRepo := data.NewAuthRepo(conn)
 	handlers.Init(config.Store, authRepo)
+	handlers.InitWebSocket(webSocketHub())
+	handlers.InitEventStream()
 
 	// Preload wiki templates
 	if err := handlers.LoadWikiTemplates(); err != nil {
@@ -64,6 +68,31 @@ func main() {
 	}
 	log.Println("database schema executed successfully")
 
+	// Persist chat messages now that their table exists
+	handlers.InitChatHistory(context.Background(), chatRetention())
+
+	// Open wiki page storage: the database, or files confined to the data directory
+	var wikiStore wiki.Store
+	switch config.WikiBackend() {
+	case "sql":
+		wikiStore = wiki.NewSQLStore(conn)
+	default:
+		fileStore, err := wiki.NewFileStore("data")
+		if err != nil {
+			log.Fatalf("failed to open wiki store: %v", err)
+		}
+		defer fileStore.Close()
+		wikiStore = fileStore
+	}
+	if err := handlers.InitWikiStore(wikiStore); err != nil {
+		log.Fatalf("failed to load wiki pages: %v", err)
+	}
+
+	// Build the full-text search index from the DB, book store and wiki pages
+	if err := handlers.RebuildSearchIndex(); err != nil {
+		log.Fatalf("failed to build search index: %v", err)
+	}
+
 	// Register routes
 	router := routes.Register()
 
@@ -0,0 +1,190 @@
+// Package binding decodes JSON (or MessagePack) request bodies into structs
+// strictly (size limited, no unknown fields, nothing after the value) and validates them
+// against their validate struct tags. Failures are reported as problem details
+// listing every invalid field (or in the format the client accepts, see
+// package respond).
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MaxBodySize is the largest request body Bind and ReadBody accept.
+const MaxBodySize = 1 << 20
+
+// Bind decodes the body of r into dst, a pointer to a struct, and validates
+// it. The body is JSON, or MessagePack (with the same field names) if its
+// Content-Type says so. If either fails it writes a problem response and returns false.
+func Bind(w http.ResponseWriter, r *http.Request, dst any) bool {
+	body := http.MaxBytesReader(w, r.Body, MaxBodySize)
+	if isMsgPack(r) {
+		return check(w, r, decodeMsgPack(body, dst), dst)
+	}
+	return check(w, r, decode(body, dst), dst)
+}
+
+// BindJSON is Bind for a body that has already been read (e.g. one produced
+// by applying a merge patch).
+func BindJSON(w http.ResponseWriter, r *http.Request, data []byte, dst any) bool {
+	return check(w, r, decode(bytes.NewReader(data), dst), dst)
+}
+
+// ReadBody reads the body of r, up to MaxBodySize. If it can't, it writes a
+// problem response and returns false.
+func ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		respond.Problem(w, r, readError(err))
+		return nil, false
+	}
+	return data, true
+}
+
+// check validates dst if decoding succeeded and writes the first failure.
+func check(w http.ResponseWriter, r *http.Request, err error, dst any) bool {
+	if err == nil {
+		err = Validate(dst)
+	}
+	if err == nil {
+		return true
+	}
+	var p *problem.Problem
+	var invalid ValidationErrors
+	switch {
+	case errors.As(err, &p):
+	case errors.As(err, &invalid):
+		p = invalid.Problem()
+	default:
+		p = problem.New(http.StatusBadRequest, err.Error())
+	}
+	respond.Problem(w, r, p)
+	return false
+}
+
+// decode reads exactly one JSON value with only known fields into dst.
+func decode(body io.Reader, dst any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return readError(err)
+		}
+		return problem.New(http.StatusBadRequest, "The request body must contain a single JSON value.")
+	}
+	return nil
+}
+
+// isMsgPack reports whether the body of r is declared as MessagePack.
+func isMsgPack(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == respond.MsgPack || mediaType == "application/x-msgpack"
+}
+
+// decodeMsgPack reads exactly one MessagePack value with only known fields
+// into dst. Fields are named by their json tags, as in decode.
+func decodeMsgPack(body io.Reader, dst any) error {
+	dec := msgpack.NewDecoder(body)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	if err := dec.Decode(dst); err != nil {
+		return msgPackError(err)
+	}
+	if _, err := dec.PeekCode(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return readError(err)
+		}
+		return problem.New(http.StatusBadRequest, "The request body must contain a single MessagePack value.")
+	}
+	return nil
+}
+
+// msgPackError turns an error from msgpack.Decoder.Decode into a problem.
+func msgPackError(err error) *problem.Problem {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, "The request body is empty.")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return problem.New(http.StatusBadRequest, "The request body is truncated MessagePack.")
+	case errors.As(err, &tooLarge):
+		return readError(err)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "msgpack: unknown field "); ok {
+		p := problem.New(http.StatusBadRequest, "The request body has a field this endpoint doesn't accept.")
+		p.Errors = []problem.FieldError{{Field: strings.Trim(field, `"`), Rule: "unknown", Message: "is not a known field"}}
+		return p
+	}
+	if strings.Contains(err.Error(), "invalid code=") { // the decoder doesn't say which field
+		return problem.New(http.StatusBadRequest, "The request body has a field of the wrong type.")
+	}
+	return problem.New(http.StatusBadRequest, "The request body is malformed MessagePack.")
+}
+
+// decodeError turns an error from json.Decoder.Decode into a problem.
+func decodeError(err error) *problem.Problem {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, "The request body is empty.")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return problem.New(http.StatusBadRequest, "The request body is truncated JSON.")
+	case errors.As(err, &syntaxErr):
+		return problem.New(http.StatusBadRequest, fmt.Sprintf("The request body is malformed JSON (at byte %d).", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		p := problem.New(http.StatusBadRequest, "The request body has a field of the wrong type.")
+		p.Errors = []problem.FieldError{{Field: typeErr.Field, Rule: "type", Message: "must be " + jsonKind(typeErr.Type)}}
+		return p
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		p := problem.New(http.StatusBadRequest, "The request body has a field this endpoint doesn't accept.")
+		p.Errors = []problem.FieldError{{Field: strings.Trim(field, `"`), Rule: "unknown", Message: "is not a known field"}}
+		return p
+	}
+	return readError(err)
+}
+
+// readError turns an error reading the body into a problem.
+func readError(err error) *problem.Problem {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return problem.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body must not exceed %d bytes.", tooLarge.Limit))
+	}
+	return problem.New(http.StatusBadRequest, "The request body couldn't be read.")
+}
+
+// jsonKind names the JSON type that decodes into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,167 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type base struct {
+	Name string `json:"name" validate:"trim,required,min=3,max=10"`
+}
+
+type signup struct {
+	base
+	Age     int      `json:"age" validate:"min=18,max=130"`
+	Email   string   `json:"email" validate:"omitempty,pattern=^[^@ ]+@[^@ ]+$"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Code    string   `json:"code" validate:"omitempty,pattern=^[A-Z]{2,3}$"`
+	Address address  `json:"address"`
+	Ignored string   `json:"-"`
+}
+
+// bind runs Bind on body and returns the problem it wrote, if any.
+func bind(t *testing.T, body string, dst any) *problem.Problem {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	if Bind(rec, req, dst) {
+		if rec.Body.Len() != 0 {
+			t.Errorf("Bind succeeded but wrote %s", rec.Body)
+		}
+		return nil
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid problem %s", rec.Body)
+	}
+	if p.Status != rec.Code || p.Instance != "/signup" || p.Type != "about:blank" {
+		t.Errorf("problem %+v doesn't match status %d", p, rec.Code)
+	}
+	return &p
+}
+
+func TestBindValid(t *testing.T) {
+	var s signup
+	if p := bind(t, `{"name":"  gopher ","age":30,"email":"g@example.com","tags":["a"],"code":"AB","address":{"city":"Dhaka"}}`+"\n", &s); p != nil {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+	if s.Name != "gopher" || s.Age != 30 || s.Address.City != "Dhaka" {
+		t.Errorf("decoded %+v", s)
+	}
+}
+
+func TestBindListsEveryInvalidField(t *testing.T) {
+	var s signup
+	p := bind(t, `{"name":" ab ","age":12,"email":"nope","tags":["a","b","c"],"code":"A,B"}`, &s)
+	if p == nil || p.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a 422 problem, got %+v", p)
+	}
+	want := []problem.FieldError{
+		{Field: "name", Rule: "min", Message: "must be at least 3 characters"},
+		{Field: "age", Rule: "min", Message: "must be at least 18"},
+		{Field: "email", Rule: "pattern", Message: "must match ^[^@ ]+@[^@ ]+$"},
+		{Field: "tags", Rule: "max", Message: "must be at most 2 items"},
+		{Field: "code", Rule: "pattern", Message: "must match ^[A-Z]{2,3}$"},
+		{Field: "address.city", Rule: "required", Message: "is required"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("errors =\n%+v\nwant\n%+v", p.Errors, want)
+	}
+}
+
+func TestBindRejectsBadBodies(t *testing.T) {
+	for _, tc := range []struct {
+		name, body string
+		status     int
+		field      string
+	}{
+		{"empty", ``, http.StatusBadRequest, ""},
+		{"malformed", `{"name":`, http.StatusBadRequest, ""},
+		{"syntax", `{"name" "x"}`, http.StatusBadRequest, ""},
+		{"wrong type", `{"name":"gopher","age":"old"}`, http.StatusBadRequest, "age"},
+		{"unknown field", `{"name":"gopher","admin":true}`, http.StatusBadRequest, "admin"},
+		{"trailing data", `{"name":"gopher","age":20,"address":{"city":"x"}} {}`, http.StatusBadRequest, ""},
+		{"trailing garbage", `{"name":"gopher","age":20,"address":{"city":"x"}}x`, http.StatusBadRequest, ""},
+		{"too large", `{"name":"` + strings.Repeat("x", MaxBodySize) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := bind(t, tc.body, &signup{})
+			if p == nil || p.Status != tc.status {
+				t.Fatalf("expected status %d, got %+v", tc.status, p)
+			}
+			if tc.field != "" && (len(p.Errors) != 1 || p.Errors[0].Field != tc.field) {
+				t.Errorf("expected an error for %s, got %+v", tc.field, p.Errors)
+			}
+		})
+	}
+}
+
+func TestBindMsgPack(t *testing.T) {
+	encode := func(v any) []byte {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	bindMsgPack := func(body []byte, dst any) (int, problem.Problem) {
+		req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/msgpack")
+		rec := httptest.NewRecorder()
+		var p problem.Problem
+		if !Bind(rec, req, dst) {
+			json.Unmarshal(rec.Body.Bytes(), &p)
+		}
+		return rec.Code, p
+	}
+
+	// Same field names, same validation as JSON
+	valid := map[string]any{"name": "  gopher ", "age": 30, "address": map[string]any{"city": "Dhaka"}}
+	var s signup
+	if status, p := bindMsgPack(encode(valid), &s); status != http.StatusOK {
+		t.Fatalf("unexpected problem %d %+v", status, p)
+	}
+	if s.Name != "gopher" || s.Age != 30 || s.Address.City != "Dhaka" {
+		t.Errorf("decoded %+v", s)
+	}
+	if status, p := bindMsgPack(encode(map[string]any{"name": "go", "age": 30}), &signup{}); status != http.StatusUnprocessableEntity || len(p.Errors) != 2 {
+		t.Errorf("expected 422 for the name and city, got %d %+v", status, p)
+	}
+
+	body := encode(valid)
+	tests := []struct {
+		name   string
+		body   []byte
+		status int
+	}{
+		{"empty", nil, http.StatusBadRequest},
+		{"truncated", body[:len(body)-3], http.StatusBadRequest},
+		{"trailing value", append(encode(valid), 0x01), http.StatusBadRequest},
+		{"unknown field", encode(map[string]any{"name": "gopher", "nickname": "g"}), http.StatusBadRequest},
+		{"wrong type", encode(map[string]any{"name": "gopher", "age": "thirty"}), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		status, p := bindMsgPack(tt.body, &signup{})
+		if status != tt.status || p.Detail == "" {
+			t.Errorf("%s: expected %d, got %d %+v", tt.name, tt.status, status, p)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package binding
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+)
+
+// ValidationErrors lists every field that failed validation.
+type ValidationErrors []problem.FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Problem reports the errors as a 422 Unprocessable Entity problem.
+func (e ValidationErrors) Problem() *problem.Problem {
+	detail := "The request body has 1 invalid field."
+	if len(e) != 1 {
+		detail = fmt.Sprintf("The request body has %d invalid fields.", len(e))
+	}
+	p := problem.New(http.StatusUnprocessableEntity, detail)
+	p.Errors = e
+	return p
+}
+
+// Validate checks the struct v points to against the comma-separated rules in
+// its fields' validate tags, in order:
+//
+//	trim        trim surrounding whitespace from a string (changes the field)
+//	omitempty   skip the remaining rules if the field is its zero value
+//	required    the field must not be its zero value
+//	min=N       at least N: characters for strings, elements for slices and maps, the value for numbers
+//	max=N       at most N, measured like min
+//	pattern=RE  a string must match the regular expression RE; must come last (RE may contain commas)
+//
+// Fields are named by their JSON names; embedded structs are validated as
+// part of the outer struct and other struct fields with a dotted prefix.
+// It returns ValidationErrors listing every invalid field, or nil.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic("binding: Validate needs a pointer to a struct, got " + rv.Type().String())
+	}
+	var errs ValidationErrors
+	validateStruct(rv.Elem(), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct validates the fields of s, naming them with prefix.
+func validateStruct(s reflect.Value, prefix string, errs *ValidationErrors) {
+	t := s.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		field := s.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			validateStruct(field, prefix, errs) // its fields are promoted, as in encoding/json
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		name = prefix + name
+
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			if fe, ok := validateField(field, tag); !ok {
+				fe.Field = name
+				*errs = append(*errs, fe)
+				continue
+			}
+		}
+		if field.Kind() == reflect.Struct {
+			validateStruct(field, name+".", errs)
+		}
+	}
+}
+
+// validateField applies a validate tag's rules to v, stopping at the first that fails.
+func validateField(v reflect.Value, tag string) (problem.FieldError, bool) {
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "pattern=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "trim":
+			if v.Kind() == reflect.String {
+				v.SetString(strings.TrimSpace(v.String()))
+			}
+		case "omitempty":
+			if v.IsZero() {
+				return problem.FieldError{}, true
+			}
+		case "required":
+			if v.IsZero() {
+				return problem.FieldError{Rule: name, Message: "is required"}, false
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("binding: invalid %s rule %q", name, rule))
+			}
+			size, unit := measure(v)
+			if limit == 1 {
+				unit = strings.TrimSuffix(unit, "s")
+			}
+			if (name == "min" && size < limit) || (name == "max" && size > limit) {
+				bound := "at least"
+				if name == "max" {
+					bound = "at most"
+				}
+				return problem.FieldError{Rule: name, Message: fmt.Sprintf("must be %s %s%s", bound, arg, unit)}, false
+			}
+		case "pattern":
+			if !compile(arg).MatchString(v.String()) {
+				return problem.FieldError{Rule: name, Message: "must match " + arg}, false
+			}
+		default:
+			panic(fmt.Sprintf("binding: unknown validation rule %q", rule))
+		}
+	}
+	return problem.FieldError{}, true
+}
+
+// measure returns what min and max compare for v, and its unit.
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	panic("binding: min and max don't apply to " + v.Type().String())
+}
+
+// patterns caches compiled pattern rules.
+var patterns sync.Map
+
+// compile returns the compiled form of a pattern rule's expression.
+func compile(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	patterns.Store(expr, re)
+	return re
+}
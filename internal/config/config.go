@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql" // ensure mysql driver is imported
 	"github.com/gorilla/sessions"
@@ -81,6 +82,38 @@ func InitSession() {
 	}
 }
 
+// WikiBackend returns where wiki pages are stored, from WIKI_STORE:
+// "file" (the default, text files in the data directory) or "sql" (the database,
+// needed when running more than one server replica).
+func WikiBackend() string {
+	switch backend := os.Getenv("WIKI_STORE"); backend {
+	case "", "file":
+		return "file"
+	case "sql":
+		return "sql"
+	default:
+		log.Fatalf("unknown WIKI_STORE %q (use \"file\" or \"sql\")", backend)
+		return ""
+	}
+}
+
+// AllowedOrigins returns the front-end origins allowed to call the API from a
+// browser, used for both CORS and WebSocket origin checks. CORS_ORIGINS holds a
+// comma-separated list; the default is the local development front end.
+func AllowedOrigins() []string {
+	value := os.Getenv("CORS_ORIGINS")
+	if value == "" {
+		return []string{"http://localhost:3000", "http://127.0.0.1:3000"}
+	}
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
 // EnsureDataDir creates the data directory with restricted permissions (owner-only).
 func EnsureDataDir() {
 	if err := os.MkdirAll("data", 0700); err != nil {
@@ -3,14 +3,22 @@ package data
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/events"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
 )
 
 var db *sql.DB
 
+// ErrAlbumNotFound is returned when no album matches the given ID.
+var ErrAlbumNotFound = newError(ErrNotFound, "album not found")
+
+// albumSelect selects album columns joined with the artist name.
+// Scan order: id, title, artist name, artist_id, price, quantity, version.
+const albumSelect = "SELECT a.id, a.title, ar.name, a.artist_id, a.price, a.quantity, a.version " +
+	"FROM album a JOIN artist ar ON ar.id = a.artist_id"
+
 // InitDBConnection sets the package-level DB variable for reuse across data access functions.
 func InitDBConnection(conn *sql.DB) {
 	db = conn
@@ -18,26 +26,39 @@ func InitDBConnection(conn *sql.DB) {
 
 // AllAlbums returns all albums in the database.
 func AllAlbums() ([]models.Album, error) {
-	rows, err := db.Query("SELECT id, title, artist, price, quantity FROM album")
+	var albums []models.Album
+	err := EachAlbum(context.Background(), func(a models.Album) error {
+		albums = append(albums, a)
+		return nil
+	})
+	return albums, err
+}
+
+// EachAlbum calls fn with every album as its row is read, so memory use
+// doesn't grow with the table. It stops at the first error fn returns, or
+// once ctx is done, and returns that error.
+func EachAlbum(ctx context.Context, fn func(models.Album) error) error {
+	rows, err := db.QueryContext(ctx, albumSelect)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var albums []models.Album
 	for rows.Next() {
 		var a models.Album
-		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.Price, &a.Quantity); err != nil {
-			return nil, err
+		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.ArtistID, &a.Price, &a.Quantity, &a.Version); err != nil {
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
 		}
-		albums = append(albums, a)
 	}
-	return albums, rows.Err()
+	return rows.Err()
 }
 
-// AlbumsByArtist returns albums filtered by the artist's name.
+// AlbumsByArtist returns albums filtered by the artist's name (joined through the artist table).
 func AlbumsByArtist(name string) ([]models.Album, error) {
-	rows, err := db.Query("SELECT id, title, artist, price, quantity FROM album WHERE artist = ?", name)
+	rows, err := db.Query(albumSelect+" WHERE ar.name = ?", name)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +67,7 @@ func AlbumsByArtist(name string) ([]models.Album, error) {
 	var albums []models.Album
 	for rows.Next() {
 		var alb models.Album
-		if err := rows.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price, &alb.Quantity); err != nil {
+		if err := rows.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.ArtistID, &alb.Price, &alb.Quantity, &alb.Version); err != nil {
 			return nil, err
 		}
 		albums = append(albums, alb)
@@ -57,8 +78,11 @@ func AlbumsByArtist(name string) ([]models.Album, error) {
 // AlbumByID retrieves a single album by its ID.
 func AlbumByID(id int64) (models.Album, error) {
 	var album models.Album
-	err := db.QueryRow("SELECT id, title, artist, price, quantity FROM album WHERE id = ?", id).
-		Scan(&album.ID, &album.Title, &album.Artist, &album.Price, &album.Quantity)
+	err := db.QueryRow(albumSelect+" WHERE a.id = ?", id).
+		Scan(&album.ID, &album.Title, &album.Artist, &album.ArtistID, &album.Price, &album.Quantity, &album.Version)
+	if err == sql.ErrNoRows {
+		return album, ErrAlbumNotFound
+	}
 	if err != nil {
 		return album, err
 	}
@@ -66,13 +90,82 @@ func AlbumByID(id int64) (models.Album, error) {
 }
 
 // AddAlbum inserts a new album and returns its inserted ID.
+// The artist is looked up by name and created if it doesn't exist yet.
 func AddAlbum(alb models.Album) (int64, error) {
-	result, err := db.Exec("INSERT INTO album (title, artist, price, quantity) VALUES (?, ?, ?, ?)",
-		alb.Title, alb.Artist, alb.Price, alb.Quantity)
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	defer tx.Rollback()
+
+	artistID, err := artistIDByName(ctx, tx, alb.Artist)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO album (title, artist_id, price, quantity) VALUES (?, ?, ?, ?)",
+		alb.Title, artistID, alb.Price, alb.Quantity)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	publishStock(events.Stock{AlbumID: id, Quantity: alb.Quantity, Version: 1})
+	return id, nil
+}
+
+// UpdateAlbum overwrites an album's editable fields and bumps its version.
+// The update only applies if the stored version still equals expectedVersion;
+// otherwise ErrVersionConflict (or ErrAlbumNotFound) is returned.
+func UpdateAlbum(ctx context.Context, alb models.Album, expectedVersion int64) (models.Album, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return alb, err
+	}
+	defer tx.Rollback()
+
+	// The artist name is authoritative; artist_id follows it
+	alb.ArtistID, err = artistIDByName(ctx, tx, alb.Artist)
+	if err != nil {
+		return alb, err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE album SET title = ?, artist_id = ?, price = ?, quantity = ?, version = version + 1 WHERE id = ? AND version = ?",
+		alb.Title, alb.ArtistID, alb.Price, alb.Quantity, alb.ID, expectedVersion)
+	if err != nil {
+		return alb, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return alb, err
+	}
+	if n == 0 {
+		// Either the row is gone or someone else updated it first
+		var current int64
+		err := tx.QueryRowContext(ctx, "SELECT version FROM album WHERE id = ?", alb.ID).Scan(&current)
+		if err == sql.ErrNoRows {
+			return alb, ErrAlbumNotFound
+		} else if err != nil {
+			return alb, err
+		}
+		return alb, ErrVersionConflict
+	}
+
+	alb.Version = expectedVersion + 1
+	if err := tx.Commit(); err != nil {
+		return alb, err
+	}
+	publishStock(events.Stock{AlbumID: alb.ID, Quantity: alb.Quantity, Version: alb.Version})
+	return alb, nil
 }
 
 // CanPurchase checks if the requested quantity is available for a given album.
@@ -81,7 +174,7 @@ func CanPurchase(id int64, quantity int64) (bool, error) {
 	err := db.QueryRow("SELECT (quantity >= ?) FROM album WHERE id = ?", quantity, id).Scan(&enough)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return false, fmt.Errorf("unknown album ID %d", id)
+			return false, ErrAlbumNotFound
 		}
 		return false, err
 	}
@@ -116,6 +209,7 @@ func GetOrdersByUser(userID int64) ([]models.GetOrder, error) {
 }
 
 // CreateOrderByUser creates an order for a user within a transaction (all-or-nothing).
+// Once committed, it publishes the new order and the album's remaining stock.
 func CreateOrderByUser(ctx context.Context, albumID, quantity, custID int64) (int64, error) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -124,19 +218,30 @@ func CreateOrderByUser(ctx context.Context, albumID, quantity, custID int64) (in
 	defer tx.Rollback()
 
 	var enough bool
-	if err := tx.QueryRowContext(ctx, "SELECT (quantity >= ?) FROM album WHERE id = ?", quantity, albumID).Scan(&enough); err != nil {
+	err = tx.QueryRowContext(ctx, "SELECT (quantity >= ?) FROM album WHERE id = ?", quantity, albumID).Scan(&enough)
+	if err == sql.ErrNoRows {
+		return 0, ErrAlbumNotFound
+	}
+	if err != nil {
 		return 0, err
 	}
 	if !enough {
-		return 0, fmt.Errorf("not enough inventory")
+		return 0, ErrNotEnoughStock
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE album SET quantity = quantity - ?, version = version + 1 WHERE id = ?", quantity, albumID); err != nil {
+		return 0, err
 	}
 
-	if _, err := tx.ExecContext(ctx, "UPDATE album SET quantity = quantity - ? WHERE id = ?", quantity, albumID); err != nil {
+	stock := events.Stock{AlbumID: albumID}
+	if err := tx.QueryRowContext(ctx, "SELECT quantity, version FROM album WHERE id = ?", albumID).
+		Scan(&stock.Quantity, &stock.Version); err != nil {
 		return 0, err
 	}
 
+	date := time.Now()
 	res, err := tx.ExecContext(ctx, "INSERT INTO album_order (album_id, cust_id, quantity, date) VALUES (?, ?, ?, ?)",
-		albumID, custID, quantity, time.Now())
+		albumID, custID, quantity, date)
 	if err != nil {
 		return 0, err
 	}
@@ -149,59 +254,72 @@ func CreateOrderByUser(ctx context.Context, albumID, quantity, custID int64) (in
 	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
+
+	events.Publish(events.UserOrdersTopic(custID), events.OrderCreated, models.GetOrder{
+		ID: orderID, AlbumID: albumID, Customer: custID, Quantity: quantity, Date: date,
+	})
+	publishStock(stock)
 	return orderID, nil
 }
 
+// publishStock announces an album's new stock level on its own topic and on the all-albums one.
+func publishStock(s events.Stock) {
+	events.Publish(events.AlbumTopic(s.AlbumID), events.StockChanged, s)
+	events.Publish(events.AlbumsTopic, events.StockChanged, s)
+}
+
 // GetCustomerName retrieves a customer's full name by ID.
 func GetCustomerName(id int64) (string, error) {
 	var name string
 	if err := db.QueryRow("SELECT full_name FROM customer WHERE id = ?", id).Scan(&name); err != nil {
 		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("customer not found")
+			return "", ErrCustomerNotFound
 		}
 		return "", err
 	}
 	return name, nil
 }
 
-// GetAlbumsAndCustomers returns albums and customers in a combined map using multiple result sets.
-func GetAlbumsAndCustomers() (map[string]any, error) {
-	rows, err := db.Query("SELECT * FROM album; SELECT * FROM customer;")
+// EachAlbumAndCustomer reads albums and customers with one query returning
+// two result sets, calling album for every album and then customer for every
+// customer as the rows are read. It stops at the first error either returns,
+// or once ctx is done, and returns that error.
+func EachAlbumAndCustomer(ctx context.Context, album func(models.Album) error, customer func(map[string]any) error) error {
+	rows, err := db.QueryContext(ctx, albumSelect+"; SELECT * FROM customer;")
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var albums []models.Album
 	for rows.Next() {
 		var a models.Album
-		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.Price, &a.Quantity); err != nil {
-			return nil, err
+		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.ArtistID, &a.Price, &a.Quantity, &a.Version); err != nil {
+			return err
+		}
+		if err := album(a); err != nil {
+			return err
 		}
-		albums = append(albums, a)
 	}
 
-	var customers []map[string]any
 	if rows.NextResultSet() {
 		for rows.Next() {
 			var id int64
 			var fullName, address, phone string
 			if err := rows.Scan(&id, &fullName, &address, &phone); err != nil {
-				return nil, err
+				return err
 			}
-			customers = append(customers, map[string]any{
+			err := customer(map[string]any{
 				"id":       id,
 				"fullName": fullName,
 				"address":  address,
 				"phone":    phone,
 			})
+			if err != nil {
+				return err
+			}
 		}
 	}
-
-	return map[string]any{
-		"albums":    albums,
-		"customers": customers,
-	}, nil
+	return rows.Err()
 }
 
 // QueryAlbumsWithTimeout queries albums with a context timeout.
@@ -209,7 +327,7 @@ func QueryAlbumsWithTimeout(ctx context.Context) ([]models.Album, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, "SELECT id, title, artist, price, quantity FROM album")
+	rows, err := db.QueryContext(ctx, albumSelect)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +336,7 @@ func QueryAlbumsWithTimeout(ctx context.Context) ([]models.Album, error) {
 	var albums []models.Album
 	for rows.Next() {
 		var a models.Album
-		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.Price, &a.Quantity); err != nil {
+		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.ArtistID, &a.Price, &a.Quantity, &a.Version); err != nil {
 			return nil, err
 		}
 		albums = append(albums, a)
@@ -1,9 +1,14 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/events"
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
@@ -38,3 +43,73 @@ func TestCanPurchaseWithMock(t *testing.T) {
 		t.Errorf("unmet expectations: %v", err)
 	}
 }
+
+func TestCreateOrderPublishesEvents(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	var got []events.Event
+	cancel := events.Subscribe("*", func(e events.Event) { got = append(got, e) })
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\(quantity >= \\?\\) FROM album WHERE id = \\?").
+		WithArgs(int64(2), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"enough"}).AddRow(true))
+	mock.ExpectExec("UPDATE album SET quantity = quantity - \\?").
+		WithArgs(int64(2), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT quantity, version FROM album WHERE id = \\?").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "version"}).AddRow(3, 5))
+	mock.ExpectExec("INSERT INTO album_order").
+		WillReturnResult(sqlmock.NewResult(41, 1))
+	mock.ExpectCommit()
+
+	id, err := CreateOrderByUser(context.Background(), 7, 2, 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 41 {
+		t.Errorf("order ID = %d, want 41", id)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("published %d events, want 3: %+v", len(got), got)
+	}
+	order, ok := got[0].Data.(models.GetOrder)
+	if got[0].Topic != "orders:user:9" || got[0].Type != events.OrderCreated || !ok || order.ID != 41 || order.Quantity != 2 {
+		t.Errorf("order event = %+v", got[0])
+	}
+	want := events.Stock{AlbumID: 7, Quantity: 3, Version: 5}
+	for i, topic := range []string{"album:7", events.AlbumsTopic} {
+		e := got[i+1]
+		if e.Topic != topic || e.Type != events.StockChanged || e.Data != want {
+			t.Errorf("stock event = %+v, want %v on %s", e, want, topic)
+		}
+	}
+}
+
+func TestFailedOrderPublishesNothing(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	published := 0
+	cancel := events.Subscribe("*", func(events.Event) { published++ })
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\(quantity >= \\?\\) FROM album WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"enough"}).AddRow(false))
+	mock.ExpectRollback()
+
+	if _, err := CreateOrderByUser(context.Background(), 7, 100, 9); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("expected an insufficient stock error, got %v", err)
+	}
+	if published != 0 {
+		t.Errorf("published %d events for a failed order", published)
+	}
+}
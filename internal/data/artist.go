@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+)
+
+// ErrArtistNotFound is returned when no artist matches the given ID.
+var ErrArtistNotFound = newError(ErrNotFound, "artist not found")
+
+// AllArtists returns all artists ordered by name.
+func AllArtists() ([]models.Artist, error) {
+	rows, err := db.Query("SELECT id, name FROM artist ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artists []models.Artist
+	for rows.Next() {
+		var a models.Artist
+		if err := rows.Scan(&a.ID, &a.Name); err != nil {
+			return nil, err
+		}
+		artists = append(artists, a)
+	}
+	return artists, rows.Err()
+}
+
+// AlbumsByArtistID returns all albums linked to an artist.
+func AlbumsByArtistID(artistID int64) ([]models.Album, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM artist WHERE id = ?", artistID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrArtistNotFound
+	}
+
+	rows, err := db.Query(albumSelect+" WHERE a.artist_id = ?", artistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	albums := []models.Album{}
+	for rows.Next() {
+		var a models.Album
+		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.ArtistID, &a.Price, &a.Quantity, &a.Version); err != nil {
+			return nil, err
+		}
+		albums = append(albums, a)
+	}
+	return albums, rows.Err()
+}
+
+// artistIDByName returns the ID of the named artist, inserting it first if needed.
+// It runs inside the caller's transaction so the album write and artist insert commit together.
+func artistIDByName(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM artist WHERE name = ?", name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO artist (name) VALUES (?)", name)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
@@ -1,35 +1,71 @@
 package data
 
 import (
-	"errors"
+	"net/http"
+	"sync"
 
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
 )
 
+var (
+	// ErrBookNotFound is returned when no book matches the given ID.
+	ErrBookNotFound = newError(ErrNotFound, "book not found")
+	// ErrVersionConflict is returned when the caller's expected version is stale.
+	// Writes carry the version they read in If-Match, so it's a failed precondition.
+	ErrVersionConflict = newStatusError(ErrConflict, "version conflict", http.StatusPreconditionFailed)
+)
+
 // In-memory store for books
 var books = []models.Book{
-	{ID: 1, Title: "Blue Train", Author: "John Coltrane", Price: 56.99},
-	{ID: 2, Title: "Jeru", Author: "Gerry Mulligan", Price: 17.99},
-	{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Author: "Sarah Vaughan", Price: 39.99},
+	{ID: 1, Title: "Blue Train", Author: "John Coltrane", Price: 56.99, AuthorIDs: []int{1}, GenreIDs: []int{1, 2}, Version: 1},
+	{ID: 2, Title: "Jeru", Author: "Gerry Mulligan", Price: 17.99, AuthorIDs: []int{2}, GenreIDs: []int{1, 3}, Version: 1},
+	{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Author: "Sarah Vaughan", Price: 39.99, AuthorIDs: []int{3, 4}, GenreIDs: []int{1, 4}, Version: 1},
 }
 
+// booksMu guards books, authors and genres so version checks and writes happen atomically.
+var booksMu sync.RWMutex
+
 // GetAllBooks returns all books
 func GetAllBooks() []models.Book {
-	return books
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
+	out := make([]models.Book, len(books))
+	for i, b := range books {
+		out[i] = cloneBook(b)
+	}
+	return out
 }
 
 // GetBookByID returns a book by ID
 func GetBookByID(id int) (*models.Book, error) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
 	for _, b := range books {
 		if b.ID == id {
+			b = cloneBook(b)
 			return &b, nil
 		}
 	}
-	return nil, errors.New("book not found")
+	return nil, ErrBookNotFound
 }
 
-// AddBook adds a new book
-func AddBook(b models.Book) models.Book {
+// AddBook adds a new book.
+// If no author IDs are given, the byline is linked to a (possibly new) author of the same name.
+func AddBook(b models.Book) (models.Book, error) {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+
+	if len(b.AuthorIDs) == 0 && b.Author != "" {
+		b.AuthorIDs = []int{findOrCreateAuthor(b.Author).ID}
+	}
+	b.AuthorIDs = dedupe(b.AuthorIDs)
+	b.GenreIDs = dedupe(b.GenreIDs)
+	if err := validateLinks(b); err != nil {
+		return b, err
+	}
+
 	// Generate a new ID
 	var maxID int // it gets the zero value of its type by default.
 	for _, book := range books {
@@ -38,14 +74,26 @@ func AddBook(b models.Book) models.Book {
 		}
 	}
 	b.ID = maxID + 1
+	b.Version = 1
 	books = append(books, b)
-	return b
+	return cloneBook(b), nil
 }
 
-// UpdateBook updates a book by ID
-func UpdateBook(id int, updated models.Book) (*models.Book, error) {
+// UpdateBook updates a book by ID.
+// Empty fields are left unchanged. If expectedVersion is non-zero it must
+// match the stored version, otherwise ErrVersionConflict is returned.
+func UpdateBook(id int, updated models.Book, expectedVersion int) (*models.Book, error) {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+
 	for i, b := range books {
 		if b.ID == id {
+			if expectedVersion != 0 && b.Version != expectedVersion {
+				return nil, ErrVersionConflict
+			}
+			if err := validateLinks(updated); err != nil {
+				return nil, err
+			}
 			if updated.Title != "" {
 				books[i].Title = updated.Title
 			}
@@ -55,19 +103,56 @@ func UpdateBook(id int, updated models.Book) (*models.Book, error) {
 			if updated.Price != 0 {
 				books[i].Price = updated.Price
 			}
-			return &books[i], nil
+			if updated.AuthorIDs != nil {
+				books[i].AuthorIDs = dedupe(updated.AuthorIDs)
+			}
+			if updated.GenreIDs != nil {
+				books[i].GenreIDs = dedupe(updated.GenreIDs)
+			}
+			books[i].Version++
+			b = cloneBook(books[i])
+			return &b, nil
 		}
 	}
-	return nil, errors.New("book not found")
+	return nil, ErrBookNotFound
+}
+
+// ReplaceBook overwrites every field of a book (used by merge-patch updates,
+// where zero values are intentional). expectedVersion works as in UpdateBook.
+func ReplaceBook(id int, replacement models.Book, expectedVersion int) (*models.Book, error) {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+
+	for i, b := range books {
+		if b.ID == id {
+			if expectedVersion != 0 && b.Version != expectedVersion {
+				return nil, ErrVersionConflict
+			}
+			replacement.AuthorIDs = dedupe(replacement.AuthorIDs)
+			replacement.GenreIDs = dedupe(replacement.GenreIDs)
+			if err := validateLinks(replacement); err != nil {
+				return nil, err
+			}
+			replacement.ID = id
+			replacement.Version = b.Version + 1
+			books[i] = replacement
+			replacement = cloneBook(replacement)
+			return &replacement, nil
+		}
+	}
+	return nil, ErrBookNotFound
 }
 
 // DeleteBook deletes a book by ID
 func DeleteBook(id int) error {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+
 	for i, b := range books {
 		if b.ID == id { // ... means: expand this slice into individual elements
 			books = append(books[:i], books[i+1:]...)
 			return nil
 		}
 	}
-	return errors.New("book not found")
+	return ErrBookNotFound
 }
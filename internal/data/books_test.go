@@ -0,0 +1,55 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+)
+
+func TestBooksByAuthorManyToMany(t *testing.T) {
+	// Book 3 is co-written by authors 3 and 4
+	for _, authorID := range []int{3, 4} {
+		got, err := BooksByAuthor(authorID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != 3 {
+			t.Errorf("author %d: expected [book 3], got %+v", authorID, got)
+		}
+	}
+
+	if _, err := BooksByAuthor(999); err != ErrAuthorNotFound {
+		t.Errorf("expected ErrAuthorNotFound, got %v", err)
+	}
+}
+
+func TestAddBookLinksAuthorByName(t *testing.T) {
+	b, err := AddBook(models.Book{Title: "Kind of Blue", Author: "miles davis", GenreIDs: []int{1, 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer DeleteBook(b.ID)
+
+	if len(b.AuthorIDs) != 1 {
+		t.Fatalf("expected one linked author, got %v", b.AuthorIDs)
+	}
+	if len(b.GenreIDs) != 1 {
+		t.Errorf("expected duplicate genre IDs to be removed, got %v", b.GenreIDs)
+	}
+
+	if _, err := AddBook(models.Book{Title: "Ghost", GenreIDs: []int{999}}); err != ErrGenreNotFound {
+		t.Errorf("expected ErrGenreNotFound, got %v", err)
+	}
+}
+
+func TestReplaceBookVersionConflict(t *testing.T) {
+	b, _ := AddBook(models.Book{Title: "Draft", Author: "Gopher"})
+	defer DeleteBook(b.ID)
+
+	if _, err := ReplaceBook(b.ID, models.Book{Title: "v2"}, b.Version); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ReplaceBook(b.ID, models.Book{Title: "stale"}, b.Version); err != ErrVersionConflict {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+}
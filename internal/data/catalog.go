@@ -0,0 +1,187 @@
+package data
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+)
+
+var (
+	// ErrAuthorNotFound is returned when no author matches the given ID.
+	ErrAuthorNotFound = newError(ErrNotFound, "author not found")
+	// ErrGenreNotFound is returned when no genre matches the given ID.
+	ErrGenreNotFound = newError(ErrNotFound, "genre not found")
+)
+
+// In-memory stores for authors and genres (guarded by booksMu, like books)
+var authors = []models.Author{
+	{ID: 1, Name: "John Coltrane"},
+	{ID: 2, Name: "Gerry Mulligan"},
+	{ID: 3, Name: "Sarah Vaughan"},
+	{ID: 4, Name: "Clifford Brown"},
+}
+
+var genres = []models.Genre{
+	{ID: 1, Name: "Jazz"},
+	{ID: 2, Name: "Hard Bop"},
+	{ID: 3, Name: "Cool Jazz"},
+	{ID: 4, Name: "Vocal Jazz"},
+}
+
+// GetAllAuthors returns all authors
+func GetAllAuthors() []models.Author {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+	return slices.Clone(authors)
+}
+
+// GetAuthorByID returns an author by ID
+func GetAuthorByID(id int) (*models.Author, error) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
+	for _, a := range authors {
+		if a.ID == id {
+			return &a, nil
+		}
+	}
+	return nil, ErrAuthorNotFound
+}
+
+// AddAuthor adds a new author, or returns the existing one with the same name
+func AddAuthor(name string) models.Author {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+	return findOrCreateAuthor(name)
+}
+
+// findOrCreateAuthor matches names case-insensitively. Caller must hold booksMu.
+func findOrCreateAuthor(name string) models.Author {
+	for _, a := range authors {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+
+	var maxID int
+	for _, a := range authors {
+		maxID = max(maxID, a.ID)
+	}
+	a := models.Author{ID: maxID + 1, Name: name}
+	authors = append(authors, a)
+	return a
+}
+
+// GetAllGenres returns all genres
+func GetAllGenres() []models.Genre {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+	return slices.Clone(genres)
+}
+
+// GetGenreByID returns a genre by ID
+func GetGenreByID(id int) (*models.Genre, error) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
+	for _, g := range genres {
+		if g.ID == id {
+			return &g, nil
+		}
+	}
+	return nil, ErrGenreNotFound
+}
+
+// AddGenre adds a new genre, or returns the existing one with the same name
+func AddGenre(name string) models.Genre {
+	booksMu.Lock()
+	defer booksMu.Unlock()
+
+	for _, g := range genres {
+		if strings.EqualFold(g.Name, name) {
+			return g
+		}
+	}
+
+	var maxID int
+	for _, g := range genres {
+		maxID = max(maxID, g.ID)
+	}
+	g := models.Genre{ID: maxID + 1, Name: name}
+	genres = append(genres, g)
+	return g
+}
+
+// BooksByAuthor returns every book linked to the given author
+func BooksByAuthor(authorID int) ([]models.Book, error) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
+	if !authorExists(authorID) {
+		return nil, ErrAuthorNotFound
+	}
+
+	result := []models.Book{}
+	for _, b := range books {
+		if slices.Contains(b.AuthorIDs, authorID) {
+			result = append(result, cloneBook(b))
+		}
+	}
+	return result, nil
+}
+
+// BooksByGenre returns every book linked to the given genre
+func BooksByGenre(genreID int) ([]models.Book, error) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+
+	if !genreExists(genreID) {
+		return nil, ErrGenreNotFound
+	}
+
+	result := []models.Book{}
+	for _, b := range books {
+		if slices.Contains(b.GenreIDs, genreID) {
+			result = append(result, cloneBook(b))
+		}
+	}
+	return result, nil
+}
+
+// validateLinks checks that every linked author and genre exists. Caller must hold booksMu.
+func validateLinks(b models.Book) error {
+	for _, id := range b.AuthorIDs {
+		if !authorExists(id) {
+			return ErrAuthorNotFound
+		}
+	}
+	for _, id := range b.GenreIDs {
+		if !genreExists(id) {
+			return ErrGenreNotFound
+		}
+	}
+	return nil
+}
+
+func authorExists(id int) bool {
+	return slices.ContainsFunc(authors, func(a models.Author) bool { return a.ID == id })
+}
+
+func genreExists(id int) bool {
+	return slices.ContainsFunc(genres, func(g models.Genre) bool { return g.ID == id })
+}
+
+// cloneBook copies a book including its link slices, so callers can't mutate the store
+func cloneBook(b models.Book) models.Book {
+	b.AuthorIDs = slices.Clone(b.AuthorIDs)
+	b.GenreIDs = slices.Clone(b.GenreIDs)
+	return b
+}
+
+// dedupe sorts IDs and removes duplicates
+func dedupe(ids []int) []int {
+	out := append([]int{}, ids...) // never nil, so JSON shows [] instead of null
+	slices.Sort(out)
+	return slices.Compact(out)
+}
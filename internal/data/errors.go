@@ -0,0 +1,57 @@
+package data
+
+import "net/http"
+
+// Kinds of failure. Every specific error of this package wraps one of them,
+// so callers can tell what went wrong without knowing each error:
+// errors.Is(ErrAlbumNotFound, ErrNotFound) is true.
+var (
+	// ErrNotFound means the record asked for doesn't exist.
+	ErrNotFound = newKind("not found", http.StatusNotFound)
+	// ErrConflict means the write clashes with the record's current state.
+	ErrConflict = newKind("conflict", http.StatusConflict)
+	// ErrInsufficientStock means an order asks for more copies than are left.
+	ErrInsufficientStock = newKind("insufficient stock", http.StatusConflict)
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the given ID.
+	ErrUserNotFound = newError(ErrNotFound, "user not found")
+	// ErrCustomerNotFound is returned when no customer matches the given ID.
+	ErrCustomerNotFound = newError(ErrNotFound, "customer not found")
+	// ErrUsernameTaken is returned when another user already has the username.
+	ErrUsernameTaken = newError(ErrConflict, "username already taken")
+	// ErrNotEnoughStock is returned when an album has fewer copies than ordered.
+	ErrNotEnoughStock = newError(ErrInsufficientStock, "not enough inventory")
+)
+
+// kindError is one of the kinds above, or an error of one of them.
+type kindError struct {
+	kind   error // nil for a kind
+	msg    string
+	status int
+}
+
+// newKind creates a kind of failure, reported with the given HTTP status.
+func newKind(msg string, status int) error {
+	return &kindError{msg: msg, status: status}
+}
+
+// newError creates an error of the given kind, reported with its status.
+func newError(kind error, msg string) error {
+	return newStatusError(kind, msg, kind.(*kindError).status)
+}
+
+// newStatusError creates an error of the given kind reported with a status
+// of its own.
+func newStatusError(kind error, msg string, status int) error {
+	return &kindError{kind: kind, msg: msg, status: status}
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// StatusCode returns the HTTP status of a response reporting the error
+// (see respond.Error).
+func (e *kindError) StatusCode() int { return e.status }
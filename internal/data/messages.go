@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+)
+
+// MessageRetention limits how many chat messages are kept.
+type MessageRetention struct {
+	MaxAge     time.Duration // delete messages older than this; 0 keeps them regardless of age
+	MaxPerRoom int           // keep at most this many messages per room; 0 means no limit
+	Interval   time.Duration // how often to clean up
+}
+
+// SaveMessage stores a chat message and returns its ID.
+func SaveMessage(ctx context.Context, m models.Message) (int64, error) {
+	res, err := db.ExecContext(ctx,
+		"INSERT INTO messages (room, client_id, user_id, username, data, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		m.Room, m.ClientID, m.UserID, m.Username, m.Data, m.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RoomMessages returns up to limit of a room's messages with IDs below before
+// (or its latest ones if before is 0), oldest first.
+func RoomMessages(ctx context.Context, room string, before int64, limit int) ([]models.Message, error) {
+	query := "SELECT id, room, client_id, user_id, username, data, created_at FROM messages WHERE room = ?"
+	args := []any{room}
+	if before > 0 {
+		query += " AND id < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []models.Message
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.Room, &m.ClientID, &m.UserID, &m.Username, &m.Data, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Newest first from the query; callers want reading order
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// PruneMessages deletes the messages r says not to keep, as of now, and
+// returns how many it deleted.
+func PruneMessages(ctx context.Context, r MessageRetention, now time.Time) (int64, error) {
+	var deleted int64
+	if r.MaxAge > 0 {
+		res, err := db.ExecContext(ctx, "DELETE FROM messages WHERE created_at < ?", now.Add(-r.MaxAge))
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if r.MaxPerRoom <= 0 {
+		return deleted, nil
+	}
+
+	rooms, err := messageRooms(ctx)
+	if err != nil {
+		return deleted, err
+	}
+	for _, room := range rooms {
+		// The newest message that no longer fits; it and everything older go
+		var cutoff int64
+		err := db.QueryRowContext(ctx,
+			"SELECT id FROM messages WHERE room = ? ORDER BY id DESC LIMIT 1 OFFSET ?", room, r.MaxPerRoom).Scan(&cutoff)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return deleted, err
+		}
+		res, err := db.ExecContext(ctx, "DELETE FROM messages WHERE room = ? AND id <= ?", room, cutoff)
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// messageRooms returns the rooms that have messages.
+func messageRooms(ctx context.Context) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT room FROM messages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []string
+	for rows.Next() {
+		var room string
+		if err := rows.Scan(&room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+// CleanUpMessages prunes messages every r.Interval until ctx is done.
+func CleanUpMessages(ctx context.Context, r MessageRetention) {
+	if r.Interval <= 0 || (r.MaxAge <= 0 && r.MaxPerRoom <= 0) {
+		return
+	}
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			n, err := PruneMessages(ctx, r, now)
+			if err != nil {
+				log.Printf("message cleanup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("message cleanup deleted %d messages", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
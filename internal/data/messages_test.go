@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// setupMessagesDB points the package at an in-memory messages table.
+func setupMessagesDB(t *testing.T) {
+	conn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	_, err = conn.Exec(`CREATE TABLE messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, room TEXT NOT NULL, client_id TEXT NOT NULL,
+		user_id INTEGER NOT NULL, username TEXT NOT NULL, data TEXT NOT NULL, created_at DATETIME NOT NULL
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitDBConnection(conn)
+}
+
+func TestRoomMessagesPaging(t *testing.T) {
+	setupMessagesDB(t)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		for _, room := range []string{"lobby", "other"} {
+			_, err := SaveMessage(ctx, models.Message{
+				Room: room, ClientID: "c1", UserID: 1, Username: "gopher",
+				Data: fmt.Sprintf(`"%s %d"`, room, i), CreatedAt: start.Add(time.Duration(i) * time.Minute),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	page, err := RoomMessages(ctx, "lobby", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Data != `"lobby 4"` || page[1].Data != `"lobby 5"` || page[0].Username != "gopher" {
+		t.Fatalf("latest page = %+v", page)
+	}
+	if !page[1].CreatedAt.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("created_at = %v", page[1].CreatedAt)
+	}
+
+	page, err = RoomMessages(ctx, "lobby", page[0].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || page[0].Data != `"lobby 1"` || page[2].Data != `"lobby 3"` {
+		t.Errorf("older page = %+v", page)
+	}
+}
+
+func TestPruneMessages(t *testing.T) {
+	setupMessagesDB(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, age := range []time.Duration{48 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour} {
+		room := "lobby"
+		if i == 3 {
+			room = "other"
+		}
+		if _, err := SaveMessage(ctx, models.Message{Room: room, Data: "1", CreatedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := PruneMessages(ctx, MessageRetention{MaxAge: 24 * time.Hour, MaxPerRoom: 1}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("deleted %d messages, want 2 (one too old, one over the room limit)", n)
+	}
+	lobby, _ := RoomMessages(ctx, "lobby", 0, 10)
+	other, _ := RoomMessages(ctx, "other", 0, 10)
+	if len(lobby) != 1 || lobby[0].ID != 3 || len(other) != 1 {
+		t.Errorf("kept lobby %+v and other %+v", lobby, other)
+	}
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
@@ -10,22 +12,34 @@ import (
 
 // GetAllUsers fetches all users from the database.
 func GetAllUsers() ([]models.User, error) {
-	rows, err := db.Query(`SELECT id, username, password, created_at FROM users`)
+	var users []models.User
+	err := EachUser(context.Background(), func(u models.User) error {
+		users = append(users, u)
+		return nil
+	})
+	return users, err
+}
+
+// EachUser calls fn with every user as its row is read, so memory use
+// doesn't grow with the table. It stops at the first error fn returns, or
+// once ctx is done, and returns that error.
+func EachUser(ctx context.Context, fn func(models.User) error) error {
+	rows, err := db.QueryContext(ctx, `SELECT id, username, password, created_at FROM users`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var users []models.User
 	for rows.Next() {
 		var u models.User
 		if err := rows.Scan(&u.ID, &u.Username, &u.Password, &u.CreatedAt); err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
 		}
-		users = append(users, u)
 	}
-	return users, rows.Err()
-
+	return rows.Err()
 }
 
 // GetUserByID fetches a user by their ID.
@@ -33,6 +47,9 @@ func GetUserByID(id int) (*models.User, error) {
 	var u models.User
 	err := db.QueryRow(`SELECT id, username, password, created_at FROM users WHERE id = ?`, id).
 		Scan(&u.ID, &u.Username, &u.Password, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +57,11 @@ func GetUserByID(id int) (*models.User, error) {
 }
 
 // CreateUser inserts a new user with hashed password and returns the new user ID.
+// ErrUsernameTaken is returned if the username is in use.
 func CreateUser(username, password string) (int64, error) {
+	if err := checkUsername(username, 0); err != nil {
+		return 0, err
+	}
 	hashed, err := HashPassword(password)
 	if err != nil {
 		return 0, err
@@ -60,8 +81,28 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
+// checkUsername returns ErrUsernameTaken if a user other than id has the username.
+// The UNIQUE index still guards against a race between this check and the write.
+func checkUsername(username string, id int) error {
+	var taken bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM users WHERE username = ? AND id <> ?`, username, id).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
 // UpdateUserByID updates username and/or password for a given user ID.
+// ErrUsernameTaken is returned if another user has the new username.
 func UpdateUserByID(id int, username, password string) error {
+	if username != "" {
+		if err := checkUsername(username, id); err != nil {
+			return err
+		}
+	}
 	if username != "" && password != "" {
 		hashed, err := HashPassword(password)
 		if err != nil {
@@ -90,6 +131,16 @@ func UpdateUserByID(id int, username, password string) error {
 
 // DeleteUserByID removes a user from the database by ID.
 func DeleteUserByID(id int) error {
-	_, err := db.Exec(`DELETE FROM users WHERE id = ?`, id)
-	return err
+	res, err := db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations bring tables created by older versions of schema.sql up to date.
+// schema.sql only creates missing tables, so columns added to it later never
+// reach an existing database on their own. Each migration checks for the old
+// layout first, so running them again does nothing.
+var migrations = []struct {
+	name string
+	run  func(*sql.DB) error
+}{
+	{"add album.version", addAlbumVersion},
+	{"move album.artist to the artist table", addAlbumArtistID},
+}
+
+// migrate runs the migrations in order.
+func migrate(db *sql.DB) error {
+	for _, m := range migrations {
+		if err := m.run(db); err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// addAlbumVersion adds the row version that album updates check (If-Match).
+func addAlbumVersion(db *sql.DB) error {
+	cols, err := columns(db, "album")
+	if err != nil || cols == nil || cols["version"] {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE album ADD COLUMN version INT NOT NULL DEFAULT 1")
+	return err
+}
+
+// addAlbumArtistID replaces the artist name stored in each album by a
+// reference to the artist table: an artist is created for every name, albums
+// get artist_id pointing at theirs, and the name column is dropped. The last
+// step is a single ALTER TABLE, so the name column only goes once every
+// album has its artist_id; until then a re-run picks up where it stopped.
+func addAlbumArtistID(db *sql.DB) error {
+	cols, err := columns(db, "album")
+	if err != nil || !cols["artist"] {
+		return err
+	}
+
+	steps := []string{
+		`CREATE TABLE IF NOT EXISTS artist (
+			id   INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL UNIQUE
+		)`,
+		"INSERT IGNORE INTO artist (name) SELECT DISTINCT artist FROM album",
+	}
+	if !cols["artist_id"] {
+		steps = append(steps, "ALTER TABLE album ADD COLUMN artist_id INT NULL AFTER title")
+	}
+	steps = append(steps,
+		"UPDATE album a JOIN artist ar ON ar.name = a.artist SET a.artist_id = ar.id",
+		`ALTER TABLE album
+			MODIFY artist_id INT NOT NULL,
+			ADD FOREIGN KEY (artist_id) REFERENCES artist(id),
+			DROP COLUMN artist`,
+	)
+	for _, stmt := range steps {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columns returns the column names of a table in the current database, or
+// nil if there is no such table yet (schema.sql will create it complete).
+func columns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols map[string]bool
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if cols == nil {
+			cols = map[string]bool{}
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectColumns expects the information_schema lookup of a table's columns.
+func expectColumns(mock sqlmock.Sqlmock, table string, cols ...string) {
+	rows := sqlmock.NewRows([]string{"column_name"})
+	for _, c := range cols {
+		rows.AddRow(c)
+	}
+	mock.ExpectQuery("SELECT column_name FROM information_schema.columns").WithArgs(table).WillReturnRows(rows)
+}
+
+func TestAddAlbumVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// An album table from before versioning gets the column
+	expectColumns(mock, "album", "id", "title", "artist_id", "price", "quantity")
+	mock.ExpectExec("ALTER TABLE album ADD COLUMN version").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := addAlbumVersion(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Up-to-date and missing tables are left alone
+	expectColumns(mock, "album", "id", "title", "artist_id", "price", "quantity", "version")
+	expectColumns(mock, "album")
+	for i := 0; i < 2; i++ {
+		if err := addAlbumVersion(db); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddAlbumArtistID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Albums that still name their artist get an artist row each and a reference to it
+	expectColumns(mock, "album", "id", "title", "artist", "price", "quantity")
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS artist").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT IGNORE INTO artist \\(name\\) SELECT DISTINCT artist FROM album").WillReturnResult(sqlmock.NewResult(3, 3))
+	mock.ExpectExec("ALTER TABLE album ADD COLUMN artist_id").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE album a JOIN artist ar ON ar.name = a.artist SET a.artist_id = ar.id").WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("ALTER TABLE album\\s+MODIFY artist_id INT NOT NULL,\\s+ADD FOREIGN KEY .*\\s+DROP COLUMN artist").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := addAlbumArtistID(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A run stopped before the column was dropped doesn't add artist_id twice
+	expectColumns(mock, "album", "id", "title", "artist", "artist_id", "price", "quantity")
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS artist").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT IGNORE INTO artist").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE album a JOIN artist").WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("ALTER TABLE album\\s+MODIFY").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := addAlbumArtistID(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Migrated albums are left alone
+	expectColumns(mock, "album", "id", "title", "artist_id", "price", "quantity", "version")
+	if err := addAlbumArtistID(db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
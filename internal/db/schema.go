@@ -7,12 +7,18 @@ import (
 )
 
 // ExecuteSchema reads a SQL file and executes its statements on the provided DB.
+// Tables created by an older schema are migrated first (see migrations), so
+// the file's seed data fits them.
 func ExecuteSchema(db *sql.DB, path string) error {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read schema file: %w", err)
 	}
 
+	if err := migrate(db); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	// Execute all statements in the file
 	if _, err := db.Exec(string(content)); err != nil {
 		return fmt.Errorf("failed to execute schema: %w", err)
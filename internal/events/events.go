@@ -0,0 +1,133 @@
+// Package events is an in-process publish/subscribe bus for domain events.
+// The data layer publishes what changed (an order was placed, an album's stock
+// moved) after committing it, and anything interested, such as the WebSocket
+// hub, subscribes without the data layer knowing about it.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	OrderCreated = "order.created" // Data is a models.GetOrder
+	StockChanged = "stock.changed" // Data is a Stock
+)
+
+// AlbumsTopic receives the StockChanged events of every album.
+const AlbumsTopic = "albums"
+
+// AlbumTopic is the topic of one album's StockChanged events.
+func AlbumTopic(albumID int64) string {
+	return fmt.Sprintf("album:%d", albumID)
+}
+
+// UserOrdersTopic is the topic of one customer's OrderCreated events.
+func UserOrdersTopic(userID int64) string {
+	return fmt.Sprintf("orders:user:%d", userID)
+}
+
+// Stock is the payload of a StockChanged event.
+type Stock struct {
+	AlbumID  int64 `json:"album_id"`
+	Quantity int64 `json:"quantity"`
+	Version  int64 `json:"version"`
+}
+
+// Event is something that happened, published on a topic. IDs increase with
+// every event published on a bus, so a consumer that saw event N can ask a
+// Replay for everything after it.
+type Event struct {
+	ID    uint64    `json:"id"`
+	Topic string    `json:"topic"`
+	Type  string    `json:"type"`
+	Data  any       `json:"data"`
+	Time  time.Time `json:"time"`
+}
+
+// Bus delivers published events to the subscribers of their topic.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[int]subscription
+	nextID int
+
+	publishing sync.Mutex // delivers one event at a time, so subscribers see IDs in order
+	lastID     uint64     // guarded by publishing
+}
+
+type subscription struct {
+	pattern string
+	fn      func(Event)
+}
+
+// NewBus creates a bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: map[int]subscription{}}
+}
+
+// Subscribe calls fn for every event whose topic matches pattern: a topic
+// name, a prefix ending in "*" (e.g. "album:*"), or "*" for everything.
+// fn runs on the publisher's goroutine, so it must not block or publish.
+// The returned function cancels the subscription.
+func (b *Bus) Subscribe(pattern string, fn func(Event)) (cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.subs[id] = subscription{pattern: pattern, fn: fn}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish numbers an event, sets its Time if it's zero and sends it to the
+// matching subscribers.
+func (b *Bus) Publish(e Event) {
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	var fns []func(Event)
+	for _, s := range b.subs {
+		if match(s.pattern, e.Topic) {
+			fns = append(fns, s.fn)
+		}
+	}
+	b.mu.RUnlock()
+
+	// Call outside the lock so subscribers may subscribe or cancel
+	for _, fn := range fns {
+		fn(e)
+	}
+}
+
+// match reports whether topic matches a subscription pattern.
+func match(pattern, topic string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(topic, prefix)
+	}
+	return pattern == topic
+}
+
+// Default is the bus the data layer publishes on.
+var Default = NewBus()
+
+// Publish publishes an event of the given type on the default bus.
+func Publish(topic, typ string, data any) {
+	Default.Publish(Event{Topic: topic, Type: typ, Data: data})
+}
+
+// Subscribe subscribes to the default bus; see Bus.Subscribe.
+func Subscribe(pattern string, fn func(Event)) (cancel func()) {
+	return Default.Subscribe(pattern, fn)
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPublishMatchesPatterns(t *testing.T) {
+	b := NewBus()
+	got := map[string][]string{}
+	for _, p := range []string{"album:1", "album:*", "*", "orders:user:1"} {
+		b.Subscribe(p, func(e Event) { got[p] = append(got[p], e.Topic) })
+	}
+
+	b.Publish(Event{Topic: AlbumTopic(1), Type: StockChanged})
+	b.Publish(Event{Topic: AlbumTopic(12), Type: StockChanged})
+	b.Publish(Event{Topic: UserOrdersTopic(2), Type: OrderCreated})
+
+	want := map[string][]string{
+		"album:1": {"album:1"},
+		"album:*": {"album:1", "album:12"},
+		"*":       {"album:1", "album:12", "orders:user:2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deliveries = %v, want %v", got, want)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	b := NewBus()
+	n := 0
+	cancel := b.Subscribe("*", func(e Event) {
+		n++
+		if e.Time.IsZero() {
+			t.Error("Publish didn't set the event's time")
+		}
+	})
+	b.Publish(Event{Topic: "albums"})
+	cancel()
+	cancel() // idempotent
+	b.Publish(Event{Topic: "albums"})
+	if n != 1 {
+		t.Errorf("subscriber called %d times, want 1", n)
+	}
+}
+
+func TestPublishNumbersEvents(t *testing.T) {
+	b := NewBus()
+	var ids []uint64
+	b.Subscribe("*", func(e Event) { ids = append(ids, e.ID) })
+	for range 3 {
+		b.Publish(Event{Topic: "albums"})
+	}
+	if !reflect.DeepEqual(ids, []uint64{1, 2, 3}) {
+		t.Errorf("IDs = %v, want 1, 2, 3", ids)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	r := NewReplay(3)
+	if evs, complete := r.Since(0); len(evs) != 0 || !complete {
+		t.Errorf("empty buffer: Since(0) = %v, %v", evs, complete)
+	}
+	for id := uint64(1); id <= 5; id++ {
+		r.Add(Event{ID: id})
+	}
+	ids := func(evs []Event) []uint64 {
+		var ids []uint64
+		for _, e := range evs {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		since    uint64
+		want     []uint64
+		complete bool
+	}{
+		{5, nil, true},
+		{3, []uint64{4, 5}, true},
+		{2, []uint64{3, 4, 5}, true},
+		{1, []uint64{3, 4, 5}, false}, // event 2 was evicted
+		{9, []uint64{3, 4, 5}, false}, // unknown ID, e.g. from before a restart
+	} {
+		evs, complete := r.Since(tc.since)
+		if !reflect.DeepEqual(ids(evs), tc.want) || complete != tc.complete {
+			t.Errorf("Since(%d) = %v, %v; want %v, %v", tc.since, ids(evs), complete, tc.want, tc.complete)
+		}
+	}
+	if r.LastID() != 5 {
+		t.Errorf("LastID() = %d, want 5", r.LastID())
+	}
+}
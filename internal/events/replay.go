@@ -0,0 +1,57 @@
+package events
+
+import "sync"
+
+// Replay remembers the most recent events so consumers that lost their
+// connection can catch up on what they missed.
+type Replay struct {
+	mu     sync.Mutex
+	buf    []Event // ring buffer; buf[start] is the oldest event
+	start  int
+	n      int
+	lastID uint64
+}
+
+// NewReplay creates a buffer holding the last size events.
+func NewReplay(size int) *Replay {
+	return &Replay{buf: make([]Event, max(size, 1))}
+}
+
+// Add appends e, evicting the oldest event if the buffer is full.
+// Events must be added in ID order, as a Bus subscriber receives them.
+func (r *Replay) Add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = e
+		r.n++
+	} else {
+		r.buf[r.start] = e
+		r.start = (r.start + 1) % len(r.buf)
+	}
+	r.lastID = e.ID
+}
+
+// Since returns the buffered events with IDs above id, oldest first.
+// complete is false if events after id have been evicted already, or if id
+// is from the future (e.g. from before a restart, when IDs began again);
+// then all buffered events are returned and the caller has missed some.
+func (r *Replay) Since(id uint64) (evs []Event, complete bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	complete = id <= r.lastID && (r.n == 0 || id+1 >= r.buf[r.start].ID)
+	for i := 0; i < r.n; i++ {
+		e := r.buf[(r.start+i)%len(r.buf)]
+		if e.ID > id || !complete {
+			evs = append(evs, e)
+		}
+	}
+	return evs, complete
+}
+
+// LastID returns the ID of the newest event added, or 0.
+func (r *Replay) LastID() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastID
+}
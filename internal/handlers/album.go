@@ -10,36 +10,37 @@ import (
 	"strings"
 	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
 	"github.com/shahinzaman102/Go_JumpStart/internal/data"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// GetAllAlbums responds with all albums in JSON format.
+// GetAllAlbums streams all albums as a JSON array, or as NDJSON, row by row.
 func GetAllAlbums(w http.ResponseWriter, r *http.Request) {
-	albums, err := data.AllAlbums()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	json.NewEncoder(w).Encode(albums)
+	s := respond.NewStream(w, r)
+	s.End(data.EachAlbum(r.Context(), func(a models.Album) error {
+		return s.Send(a)
+	}))
 }
 
 // GetAlbumsByArtist responds with albums filtered by artist name.
 func GetAlbumsByArtist(w http.ResponseWriter, r *http.Request) {
 	name := strings.TrimSpace(chi.URLParam(r, "name"))
 	if name == "" {
-		http.Error(w, "Artist name is required", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Artist name is required."))
 		return
 	}
 
 	albums, err := data.AlbumsByArtist(name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(albums)
+	respond.Write(w, r, http.StatusOK, albums)
 }
 
 // GetAlbumByID responds with a single album by its ID.
@@ -47,51 +48,97 @@ func GetAlbumByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "Invalid album ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid album ID."))
 		return
 	}
 
 	album, err := data.AlbumByID(id)
 	if err != nil {
-		http.Error(w, "Album not found", http.StatusNotFound)
+		respond.Error(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(album)
+	w.Header().Set("ETag", etag(album.Version))
+	respond.Write(w, r, http.StatusOK, album)
 }
 
 // CreateAlbum handles adding a new album to the database.
 func CreateAlbum(w http.ResponseWriter, r *http.Request) {
 	var album models.Album
-	if err := json.NewDecoder(r.Body).Decode(&album); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !binding.Bind(w, r, &album) { // validated against the validate tags of models.Album
 		return
 	}
 
-	// Trim whitespace
-	album.Title = strings.TrimSpace(album.Title)
-	album.Artist = strings.TrimSpace(album.Artist)
+	id, err := data.AddAlbum(album)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	album.ID = id
+	indexAlbum(album)
+	respond.Write(w, r, http.StatusOK, album)
+}
 
-	// Validation
-	if album.Title == "" || album.Artist == "" {
-		http.Error(w, "Title and Artist are required", http.StatusBadRequest)
+// PatchAlbum applies a JSON Merge Patch (RFC 7396) to an existing album.
+// If-Match is honoured: a stale version returns 412 Precondition Failed.
+func PatchAlbum(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid album ID."))
 		return
 	}
-	if len(album.Title) > 200 {
-		http.Error(w, "Title must be 1-200 characters", http.StatusBadRequest)
+
+	if !isMergePatch(r) {
+		respond.Problem(w, r, errNotMergePatch)
 		return
 	}
-	if len(album.Artist) > 100 {
-		http.Error(w, "Artist must be 1-100 characters", http.StatusBadRequest)
+
+	current, err := data.AlbumByID(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	expectedVersion, ok := ifMatchVersion(r, current.Version)
+	if !ok {
+		respond.Problem(w, r, errIfMatchFailed)
 		return
 	}
+	if expectedVersion == 0 {
+		expectedVersion = current.Version // still guard against writes between read and update
+	}
 
-	id, err := data.AddAlbum(album)
+	patch, ok := binding.ReadBody(w, r)
+	if !ok {
+		return
+	}
+
+	original, _ := json.Marshal(current)
+	merged, err := applyMergePatch(original, patch)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "The patch is not valid JSON."))
+		return
+	}
+
+	// The patched album must be as valid as a new one
+	var album models.Album
+	if !binding.BindJSON(w, r, merged, &album) {
 		return
 	}
 	album.ID = id
-	json.NewEncoder(w).Encode(album)
+
+	album, err = data.UpdateAlbum(r.Context(), album, expectedVersion)
+	if err == data.ErrVersionConflict {
+		respond.Problem(w, r, problem.New(http.StatusPreconditionFailed, "The album was modified by another request."))
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	indexAlbum(album)
+	w.Header().Set("ETag", etag(album.Version))
+	respond.Write(w, r, http.StatusOK, album)
 }
 
 // CanPurchaseAlbum checks if the requested quantity can be purchased.
@@ -99,43 +146,39 @@ func CanPurchaseAlbum(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "Invalid album ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid album ID."))
 		return
 	}
 
 	qtyStr := r.URL.Query().Get("qty")
 	if qtyStr == "" {
-		http.Error(w, "Quantity parameter 'qty' is required", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Quantity parameter 'qty' is required."))
 		return
 	}
 
 	qty, err := strconv.ParseInt(qtyStr, 10, 64)
 	if err != nil || qty <= 0 {
-		http.Error(w, "Quantity must be a positive integer", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Quantity must be a positive integer."))
 		return
 	}
 
 	ok, err := data.CanPurchase(id, qty)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]bool{"canPurchase": ok})
+	respond.Write(w, r, http.StatusOK, map[string]bool{"canPurchase": ok})
 }
 
-// GetOrdersByUser serves the last 10 orders for a logged-in user (HTML page).
+// GetOrdersByUser serves the last 10 orders for a logged-in user: an HTML page
+// for browsers, the list itself for clients that accept JSON or MessagePack.
 func GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
 	session, _ := store.Get(r, "session")
 	auth, authOk := session.Values["authenticated"].(bool)
 	userID, idOk := session.Values["user_id"].(int64)
 
 	if !authOk || !auth || !idOk {
-		session.Values["redirect_after_login"] = "/orders"
-		session.Save(r, w)
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Header().Set("Content-Type", "text/html")
-		tmpl := template.Must(template.ParseFiles("templates/login_required.html"))
-		tmpl.Execute(w, map[string]string{"Resource": "Orders API"})
+		loginRequired(w, r, "Orders API", "/orders")
 		return
 	}
 
@@ -148,7 +191,7 @@ func GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
 
 		orders, err = data.GetOrdersByUser(userID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respond.Error(w, r, err)
 			return
 		}
 
@@ -160,10 +203,32 @@ func GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
 		log.Println("Returning data from cache...")
 	}
 
+	// Browsers get the page, API clients the orders (JSON or MessagePack)
+	respond.VaryAccept(w)
+	if respond.Negotiate(r, respond.HTML, respond.JSON, respond.MsgPack) != respond.HTML {
+		if orders == nil {
+			orders = []models.GetOrder{}
+		}
+		respond.Write(w, r, http.StatusOK, orders)
+		return
+	}
+
+	// Current stock of the ordered albums; the page keeps it live over /ws
+	stock := map[int64]int64{}
+	for _, o := range orders {
+		if _, ok := stock[o.AlbumID]; ok {
+			continue
+		}
+		if album, err := data.AlbumByID(o.AlbumID); err == nil {
+			stock[o.AlbumID] = album.Quantity
+		}
+	}
+
 	w.Header().Set("Content-Type", "text/html")
 	tmpl := template.Must(template.ParseFiles("templates/orders.html"))
 	tmpl.Execute(w, map[string]any{
 		"Orders": orders,
+		"Stock":  stock,
 	})
 }
 
@@ -174,38 +239,22 @@ func CreateOrderByUser(w http.ResponseWriter, r *http.Request) {
 	auth, authOk := session.Values["authenticated"].(bool)
 
 	if !ok || !authOk || !auth {
-		session.Values["redirect_after_login"] = "/orders"
-		session.Save(r, w)
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte("Unauthorized: You must log in first to create an order.\n" +
-			"Visit /login to log in and then retry your request."))
+		loginRequired(w, r, "Orders API", "/orders")
 		return
 	}
 
 	var order models.OrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	// Validation
-	if order.AlbumID <= 0 {
-		http.Error(w, "AlbumID must be a positive integer", http.StatusBadRequest)
-		return
-	}
-	if order.Quantity <= 0 {
-		http.Error(w, "Quantity must be a positive integer", http.StatusBadRequest)
+	if !binding.Bind(w, r, &order) {
 		return
 	}
 
 	// Always use session user
 	order.Customer = userID
 
-	// Insert into DB
+	// Insert into DB; an unknown album is a 404, too few copies a 409
 	id, err := data.CreateOrderByUser(r.Context(), order.AlbumID, order.Quantity, order.Customer)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
@@ -233,9 +282,7 @@ func CreateOrderByUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Respond with JSON
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
+	respond.Write(w, r, http.StatusCreated, map[string]any{
 		"order_id": id,
 		"message":  "Order created successfully",
 	})
@@ -246,35 +293,42 @@ func GetCustomerName(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid customer ID."))
 		return
 	}
 
 	name, err := data.GetCustomerName(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"name": name})
+	respond.WriteJSON(w, http.StatusOK, map[string]string{"name": name})
 }
 
-// HandleMultipleResultSets demonstrates fetching multiple result sets (albums + customers).
+// HandleMultipleResultSets demonstrates fetching multiple result sets (albums + customers),
+// streamed as {"albums":[...],"customers":[...]} while the rows are read.
 func HandleMultipleResultSets(w http.ResponseWriter, r *http.Request) {
-	result, err := data.GetAlbumsAndCustomers()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	s := respond.NewStream(w, r)
+	s.Field("albums")
+	err := data.EachAlbumAndCustomer(r.Context(), func(a models.Album) error {
+		return s.Send(a)
+	}, func(c map[string]any) error {
+		s.Field("customers")
+		return s.Send(c)
+	})
+	if err == nil {
+		s.Field("customers") // even when there are none
 	}
-	json.NewEncoder(w).Encode(result)
+	s.End(err)
 }
 
 // QueryWithTimeout executes a DB query with a timeout context.
 func QueryWithTimeout(w http.ResponseWriter, r *http.Request) {
 	albums, err := data.QueryAlbumsWithTimeout(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+		respond.Error(w, r, err) // a 504 once the timeout is up
 		return
 	}
-	json.NewEncoder(w).Encode(albums)
+	respond.Write(w, r, http.StatusOK, albums)
 }
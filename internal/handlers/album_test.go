@@ -1,17 +1,26 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/shahinzaman102/Go_JumpStart/internal/data"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/sessions"
+	"github.com/vmihailenco/msgpack/v5"
 	_ "modernc.org/sqlite"
 )
 
@@ -21,17 +30,24 @@ func setupTestDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatal(err)
 	}
+	db.SetMaxOpenConns(1) // each new :memory: connection would be a separate, empty DB
 
-	_, err = db.Exec(`CREATE TABLE album (
+	_, err = db.Exec(`CREATE TABLE artist (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT, artist TEXT, price REAL, quantity INTEGER
+		name TEXT NOT NULL UNIQUE
+	);
+	CREATE TABLE album (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT, artist_id INTEGER REFERENCES artist(id), price REAL, quantity INTEGER,
+		version INTEGER NOT NULL DEFAULT 1
 	)`)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = db.Exec(`INSERT INTO album (title, artist, price, quantity)
-		VALUES ("Go Beats", "Gopher", 9.99, 5)`)
+	_, err = db.Exec(`INSERT INTO artist (name) VALUES ("Gopher");
+		INSERT INTO album (title, artist_id, price, quantity)
+		VALUES ("Go Beats", 1, 9.99, 5)`)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -71,4 +87,326 @@ func TestGetAlbumByID(t *testing.T) {
 	if strings.ToLower(alb.Artist) != "gopher" {
 		t.Errorf("expected artist Gopher, got %s", alb.Artist)
 	}
+	if got := resp.Header.Get("ETag"); got != `"1"` {
+		t.Errorf("expected ETag \"1\", got %s", got)
+	}
+}
+
+func TestGetAlbumNotFound(t *testing.T) {
+	setupAlbumHandlerDB(t)
+
+	r := chi.NewRouter()
+	r.Get("/albums/{id}", GetAlbumByID)
+
+	// API clients get problem details, browsers an error page
+	req := httptest.NewRequest(http.MethodGet, "/albums/42", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("expected a 404 problem, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected a 404 page, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestPatchAlbumIfMatch(t *testing.T) {
+	setupAlbumHandlerDB(t)
+
+	r := chi.NewRouter()
+	r.Patch("/albums/{id}", PatchAlbum)
+
+	patch := func(ifMatch, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPatch, "/albums/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	// Current version → applied, price can be set to 0
+	resp := patch(`"1"`, `{"price": 0}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var alb models.Album
+	if err := json.NewDecoder(resp.Body).Decode(&alb); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if alb.Price != 0 || alb.Version != 2 || alb.Title != "Go Beats" {
+		t.Errorf("unexpected album after patch: %+v", alb)
+	}
+
+	// Stale version → 412
+	resp = patch(`"1"`, `{"title": "Other"}`)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", resp.StatusCode)
+	}
+
+	// A list with the current version, weakened by a proxy → applied
+	resp = patch(`"9", W/"2"`, `{"quantity": 4}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for a list naming the current version, got %d", resp.StatusCode)
+	}
+
+	// A tag that isn't a version can't match → 412, not 400
+	resp = patch(`"abc"`, `{"quantity": 5}`)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", resp.StatusCode)
+	}
+
+	// New artist name → artist row created and linked
+	resp = patch(`"3"`, `{"artist": "Gophers United"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	alb = models.Album{}
+	json.NewDecoder(resp.Body).Decode(&alb)
+	if alb.ArtistID != 2 || alb.Artist != "Gophers United" {
+		t.Errorf("expected new artist 2, got %+v", alb)
+	}
+}
+
+func TestCreateAlbumValidation(t *testing.T) {
+	setupAlbumHandlerDB(t)
+
+	r := chi.NewRouter()
+	r.Post("/albums", CreateAlbum)
+	post := func(body string) (int, problem.Problem) {
+		req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var p problem.Problem
+		json.NewDecoder(w.Body).Decode(&p)
+		return w.Code, p
+	}
+
+	// Every invalid field is reported at once
+	status, p := post(`{"title": "` + strings.Repeat("x", 201) + `", "artist": "  ", "price": -1}`)
+	if status != http.StatusUnprocessableEntity || len(p.Errors) != 3 {
+		t.Fatalf("expected 422 with 3 field errors, got %d %+v", status, p)
+	}
+	for i, field := range []string{"title", "artist", "price"} {
+		if p.Errors[i].Field != field {
+			t.Errorf("error %d is for %s, want %s", i, p.Errors[i].Field, field)
+		}
+	}
+
+	if status, p := post(`{"title": "Go", "artist": "Gopher", "genre": "jazz"}`); status != http.StatusBadRequest || p.Errors[0].Rule != "unknown" {
+		t.Errorf("expected 400 for an unknown field, got %d %+v", status, p)
+	}
+
+	if status, _ := post(`{"title": " Go Live ", "artist": "Gopher", "quantity": 3}`); status != http.StatusOK {
+		t.Errorf("expected a valid album to be created, got %d", status)
+	}
+	alb, _ := data.AlbumByID(2)
+	if alb.Title != "Go Live" || alb.ArtistID != 1 {
+		t.Errorf("expected a trimmed title and the existing artist, got %+v", alb)
+	}
+}
+
+// fetchAs serves req to h, accepting mediaType (JSON or MessagePack), and
+// decodes the response into v.
+func fetchAs(t *testing.T, h http.Handler, req *http.Request, mediaType string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	req.Header.Set("Accept", mediaType)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != mediaType {
+		t.Fatalf("%s %s: expected %s, got %d %q: %s", req.Method, req.URL, mediaType, w.Code, ct, w.Body)
+	}
+	var err error
+	if mediaType == respond.MsgPack {
+		dec := msgpack.NewDecoder(w.Body)
+		dec.SetCustomStructTag("json")
+		err = dec.Decode(v)
+	} else {
+		err = json.NewDecoder(w.Body).Decode(v)
+	}
+	if err != nil {
+		t.Fatalf("decoding %s: %v", mediaType, err)
+	}
+	return w
+}
+
+// msgPackRequest creates a request with v encoded as MessagePack as its body.
+func msgPackRequest(t *testing.T, method, target string, v any) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	enc := msgpack.NewEncoder(&body)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, target, &body)
+	req.Header.Set("Content-Type", respond.MsgPack)
+	return req
+}
+
+func TestAlbumsMsgPack(t *testing.T) {
+	setupAlbumHandlerDB(t)
+
+	r := chi.NewRouter()
+	r.Get("/albums/{id}", GetAlbumByID)
+	r.Post("/albums", CreateAlbum)
+
+	var viaJSON, viaMsgPack models.Album
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/albums/1", nil), respond.JSON, &viaJSON)
+	w := fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/albums/1", nil), respond.MsgPack, &viaMsgPack)
+	if viaJSON.Title != "Go Beats" || !reflect.DeepEqual(viaJSON, viaMsgPack) {
+		t.Errorf("MessagePack %+v differs from JSON %+v", viaMsgPack, viaJSON)
+	}
+	if w.Header().Get("Vary") != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", w.Header().Get("Vary"))
+	}
+
+	// A MessagePack body is decoded and validated like a JSON one
+	want := models.Album{Title: "Go Live", Artist: "Gopher", Price: 12.5, Quantity: 3}
+	var created models.Album
+	fetchAs(t, r, msgPackRequest(t, http.MethodPost, "/albums", want), respond.MsgPack, &created)
+	want.ID = 2
+	if created != want {
+		t.Errorf("created %+v, want %+v", created, want)
+	}
+
+	req := msgPackRequest(t, http.MethodPost, "/albums", map[string]any{"title": "", "artist": "Gopher", "genre": "jazz"})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var p problem.Problem
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "genre" {
+		t.Errorf("expected 400 for the unknown field, got %d %+v", w.Code, p)
+	}
+}
+
+func TestOrdersMsgPack(t *testing.T) {
+	db := setupTestDB(t)
+	data.InitDBConnection(db)
+	if _, err := db.Exec(`CREATE TABLE album_order (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, album_id INTEGER, cust_id INTEGER, quantity INTEGER, date DATETIME
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	store = sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	t.Cleanup(func() { store = nil })
+	data.InitCache()
+	data.SetOrdersCache("orders:user:7:last10", []models.GetOrder{}) // skips the slow query
+
+	// Log user 7 in
+	login := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, _ := store.Get(login, "session")
+	session.Values["authenticated"] = true
+	session.Values["user_id"] = int64(7)
+	session.Save(login, rec)
+	cookie := rec.Result().Cookies()[0]
+
+	r := chi.NewRouter()
+	r.Get("/orders", GetOrdersByUser)
+	r.Post("/orders", CreateOrderByUser)
+
+	req := msgPackRequest(t, http.MethodPost, "/orders", models.OrderRequest{AlbumID: 1, Quantity: 2})
+	req.AddCookie(cookie)
+	var created map[string]any
+	if w := fetchAs(t, r, req, respond.MsgPack, &created); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+
+	var viaJSON, viaMsgPack []models.GetOrder
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.AddCookie(cookie)
+	fetchAs(t, r, req, respond.JSON, &viaJSON)
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.AddCookie(cookie)
+	fetchAs(t, r, req, respond.MsgPack, &viaMsgPack)
+	if len(viaJSON) != 1 || len(viaMsgPack) != 1 {
+		t.Fatalf("expected the new order in both, got %+v and %+v", viaJSON, viaMsgPack)
+	}
+	if !viaJSON[0].Date.Equal(viaMsgPack[0].Date) {
+		t.Errorf("dates differ: %v and %v", viaJSON[0].Date, viaMsgPack[0].Date)
+	}
+	viaJSON[0].Date, viaMsgPack[0].Date = time.Time{}, time.Time{}
+	if viaJSON[0] != viaMsgPack[0] || viaJSON[0].AlbumID != 1 || viaJSON[0].Quantity != 2 {
+		t.Errorf("MessagePack %+v differs from JSON %+v", viaMsgPack[0], viaJSON[0])
+	}
+
+	// Only 3 copies are left
+	req = msgPackRequest(t, http.MethodPost, "/orders", models.OrderRequest{AlbumID: 1, Quantity: 4})
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for insufficient stock, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestAlbumsStream(t *testing.T) {
+	db := setupTestDB(t)
+	data.InitDBConnection(db)
+	for i := 0; i < 249; i++ { // 250 albums, more than are written between flushes
+		if _, err := db.Exec(`INSERT INTO album (title, artist_id, price, quantity) VALUES (?, 1, 1, 1)`, "Album"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var albums []models.Album
+	w := fetchAs(t, http.HandlerFunc(GetAllAlbums), httptest.NewRequest(http.MethodGet, "/albums", nil), respond.JSON, &albums)
+	if len(albums) != 250 || albums[0].Title != "Go Beats" || albums[249].ID != 250 {
+		t.Fatalf("expected the 250 albums, got %d", len(albums))
+	}
+	if !w.Flushed {
+		t.Error("expected the response to be flushed while streaming")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", respond.NDJSON)
+	w = httptest.NewRecorder()
+	GetAllAlbums(w, req)
+	if w.Header().Get("Content-Type") != respond.NDJSON {
+		t.Fatalf("expected NDJSON, got %q", w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 250 {
+		t.Fatalf("expected a line per album, got %d", len(lines))
+	}
+	var a models.Album
+	if err := json.Unmarshal([]byte(lines[1]), &a); err != nil || a.ID != 2 {
+		t.Errorf("unexpected line %q: %v", lines[1], err)
+	}
+
+	// MessagePack clients get one value per album
+	req.Header.Set("Accept", respond.MsgPack)
+	w = httptest.NewRecorder()
+	GetAllAlbums(w, req)
+	if w.Header().Get("Content-Type") != respond.MsgPack {
+		t.Fatalf("expected MessagePack, got %q", w.Header().Get("Content-Type"))
+	}
+	dec := msgpack.NewDecoder(w.Body)
+	dec.SetCustomStructTag("json")
+	var n int
+	for ; ; n++ {
+		var a models.Album
+		if err := dec.Decode(&a); err == io.EOF {
+			break
+		} else if err != nil || a.ID != int64(n+1) {
+			t.Fatalf("unexpected album %d %+v: %v", n, a, err)
+		}
+	}
+	if n != 250 {
+		t.Errorf("expected the 250 albums, got %d", n)
+	}
+
+	// A client that has gone gets nothing more
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	w = httptest.NewRecorder()
+	GetAllAlbums(w, req.WithContext(ctx))
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no body once the client has gone, got %d bytes", w.Body.Len())
+	}
 }
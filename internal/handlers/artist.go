@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetArtists responds with all artists in JSON format.
+func GetArtists(w http.ResponseWriter, r *http.Request) {
+	artists, err := data.AllArtists()
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	respond.Write(w, r, http.StatusOK, artists)
+}
+
+// GetAlbumsByArtistID responds with all albums linked to an artist.
+func GetAlbumsByArtistID(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid artist ID."))
+		return
+	}
+
+	albums, err := data.AlbumsByArtistID(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	respond.Write(w, r, http.StatusOK, albums)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+	"github.com/shahinzaman102/Go_JumpStart/internal/wiki"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAttachments handles GET /attachments/{title}
+// Shows a page's attachments with upload and delete forms
+func ListAttachments(w http.ResponseWriter, r *http.Request) {
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
+	if _, err := wikiStore.Load(title); err == wiki.ErrPageNotFound {
+		respond.NotFound(w, r)
+		return
+	} else if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	attachments, err := wikiStore.Attachments(title)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	err = wikiTemplates.ExecuteTemplate(w, "attachments.html", map[string]any{
+		"Title":       title,
+		"Attachments": attachments,
+		"MaxSizeMB":   wiki.MaxAttachmentSize >> 20,
+	})
+	if err != nil {
+		respond.Error(w, r, err)
+	}
+}
+
+// UploadAttachment handles POST /attachments/{title}
+// Stores the multipart "file" field beside the page
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
+
+	// Leave some room for the multipart headers around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, wiki.MaxAttachmentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respond.Problem(w, r, problem.New(http.StatusRequestEntityTooLarge, wiki.ErrAttachmentTooLarge.Error()))
+			return
+		}
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Missing file."))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, wiki.MaxAttachmentSize+1))
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	_, err = wikiStore.SaveAttachment(title, header.Filename, content)
+	switch err {
+	case nil:
+	case wiki.ErrPageNotFound:
+		respond.Problem(w, r, problem.New(http.StatusNotFound, err.Error()))
+		return
+	case wiki.ErrAttachmentTooLarge:
+		respond.Problem(w, r, problem.New(http.StatusRequestEntityTooLarge, err.Error()))
+		return
+	case wiki.ErrInvalidAttachment:
+		respond.Problem(w, r, problem.New(http.StatusUnsupportedMediaType, err.Error()))
+		return
+	default:
+		respond.Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, wikiURL("attachments", title), http.StatusSeeOther)
+}
+
+// ServeAttachment handles GET /files/{title}/{name}
+// Images are shown inline; everything else is downloaded
+func ServeAttachment(w http.ResponseWriter, r *http.Request) {
+	title, name, ok := attachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	content, att, err := wikiStore.Attachment(title, name)
+	if err == wiki.ErrAttachmentNotFound {
+		respond.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	disposition := "attachment"
+	if att.Image {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": att.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, att.Name, att.Modified, bytes.NewReader(content))
+}
+
+// DeleteAttachment handles POST /delete-attachment/{title}/{name}
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	title, name, ok := attachmentParams(w, r)
+	if !ok {
+		return
+	}
+
+	err := wikiStore.DeleteAttachment(title, name)
+	if err == wiki.ErrAttachmentNotFound {
+		respond.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, wikiURL("attachments", title), http.StatusSeeOther)
+}
+
+// attachmentParams splits the wildcard "{title}/{name}" part of the URL and
+// validates both with the same rules as page titles.
+// On failure it writes a 400 response and returns false.
+func attachmentParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	rest := chi.URLParam(r, "*")
+	i := strings.LastIndex(rest, "/")
+	if i < 0 {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid attachment path."))
+		return "", "", false
+	}
+	title, err := url.PathUnescape(rest[:i])
+	if err != nil || wiki.ValidateTitle(title) != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid page title."))
+		return "", "", false
+	}
+	name, err := url.PathUnescape(rest[i+1:])
+	if err != nil || wiki.ValidateAttachmentName(name) != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid attachment name."))
+		return "", "", false
+	}
+	return title, name, true
+}
@@ -6,6 +6,8 @@ import (
 
 	assets "github.com/shahinzaman102/Go_JumpStart"
 	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/gorilla/sessions"
 )
@@ -30,15 +32,20 @@ func Login(w http.ResponseWriter, r *http.Request) {
 
 	ok, err := authRepo.VerifyUser(username, password)
 	if err != nil || !ok {
-		tmpl := template.Must(template.ParseFS(assets.Templates, "templates/unauthorized.html"))
-		w.WriteHeader(http.StatusUnauthorized)
-		tmpl.Execute(w, nil)
+		if respond.Negotiate(r, respond.JSON, respond.HTML) == respond.HTML {
+			tmpl := template.Must(template.ParseFS(assets.Templates, "templates/unauthorized.html"))
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			tmpl.Execute(w, nil)
+			return
+		}
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Invalid username or password."))
 		return
 	}
 
 	userID, err := authRepo.GetUserID(username)
 	if err != nil {
-		http.Error(w, "Failed to fetch user ID", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
@@ -61,13 +68,31 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := session.Save(r, w); err != nil {
-		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
 	http.Redirect(w, r, redirectPath, http.StatusSeeOther)
 }
 
+// loginRequired responds to a request that needs a logged-in user: browsers
+// get the login page link, API clients a 401 problem. The session remembers
+// redirect so logging in returns to it.
+func loginRequired(w http.ResponseWriter, r *http.Request, resource, redirect string) {
+	session, _ := store.Get(r, "session")
+	session.Values["redirect_after_login"] = redirect
+	session.Save(r, w)
+
+	if respond.Negotiate(r, respond.JSON, respond.HTML) == respond.HTML {
+		tmpl := template.Must(template.ParseFS(assets.Templates, "templates/login_required.html"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		tmpl.Execute(w, map[string]string{"Resource": resource})
+		return
+	}
+	respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Log in at /login to use the "+resource+"."))
+}
+
 // LoginForm renders the login page with an optional redirect.
 func LoginForm(w http.ResponseWriter, r *http.Request) {
 	redirect := r.URL.Query().Get("redirect")
@@ -86,7 +111,7 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	session.Values = make(map[any]any)
 
 	if err := session.Save(r, w); err != nil {
-		http.Error(w, "Failed to clear session", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
@@ -4,136 +4,229 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
 	"github.com/shahinzaman102/Go_JumpStart/internal/data"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// newBook is the body of POST /books: a book, whose author is required too.
+// It doesn't embed models.Book to override Author, since the MessagePack
+// decoder would warn about the duplicate field.
+type newBook struct {
+	Title     string  `json:"title" validate:"trim,required,max=200"`
+	Author    string  `json:"author" validate:"trim,required,max=100"`
+	Price     float64 `json:"price" validate:"min=0"`
+	AuthorIDs []int   `json:"author_ids"`
+	GenreIDs  []int   `json:"genre_ids"`
+}
+
+// bookUpdate is the body of PUT /books/{id}. Fields left out (or empty, or
+// a zero price) keep their current values.
+type bookUpdate struct {
+	Title     string  `json:"title" validate:"trim,max=200"`
+	Author    string  `json:"author" validate:"trim,max=100"`
+	Price     float64 `json:"price" validate:"min=0"`
+	AuthorIDs []int   `json:"author_ids"`
+	GenreIDs  []int   `json:"genre_ids"`
+}
+
+// errUnknownBookLinks is the response to a book naming authors or genres that don't exist.
+var errUnknownBookLinks = problem.New(http.StatusBadRequest, "Unknown author or genre ID.")
+
 // GetBooks returns all books in JSON format.
 func GetBooks(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data.GetAllBooks())
+	respond.Write(w, r, http.StatusOK, data.GetAllBooks())
 }
 
 // GetBookByID returns a single book by ID.
 func GetBookByID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"message": "invalid book ID"}`, http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid book ID."))
 		return
 	}
 
 	book, err := data.GetBookByID(id)
 	if err != nil {
-		http.Error(w, `{"message": "book not found"}`, http.StatusNotFound)
+		respond.Error(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(book)
+	w.Header().Set("ETag", etag(int64(book.Version)))
+	respond.Write(w, r, http.StatusOK, book)
 }
 
 // PostBook creates a new book.
 func PostBook(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	var newBook models.Book
-	if err := json.NewDecoder(r.Body).Decode(&newBook); err != nil {
-		http.Error(w, `{"message": "invalid JSON"}`, http.StatusBadRequest)
+	var input newBook
+	if !binding.Bind(w, r, &input) {
 		return
 	}
 
-	// Trim whitespace
-	newBook.Title = strings.TrimSpace(newBook.Title)
-	newBook.Author = strings.TrimSpace(newBook.Author)
+	book, err := data.AddBook(models.Book{
+		Title:     input.Title,
+		Author:    input.Author,
+		Price:     input.Price,
+		AuthorIDs: input.AuthorIDs,
+		GenreIDs:  input.GenreIDs,
+	})
+	if err != nil {
+		bookWriteError(w, r, err)
+		return
+	}
+	indexBook(book)
+	respond.Write(w, r, http.StatusCreated, book)
+}
+
+// UpdateBook updates an existing book by ID.
+func UpdateBook(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid book ID."))
+		return
+	}
+
+	current, err := data.GetBookByID(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	expectedVersion, ok := ifMatchVersion(r, int64(current.Version))
+	if !ok {
+		respond.Problem(w, r, errIfMatchFailed)
+		return
+	}
+	if expectedVersion == 0 {
+		expectedVersion = int64(current.Version) // as in PatchBook
+	}
 
-	// Validation
-	if newBook.Title == "" || newBook.Author == "" {
-		http.Error(w, `{"message": "Title and Author are required"}`, http.StatusBadRequest)
+	var input bookUpdate
+	if !binding.Bind(w, r, &input) {
 		return
 	}
-	if len(newBook.Title) > 200 {
-		http.Error(w, `{"message": "Title must be 1-200 characters"}`, http.StatusBadRequest)
+	if input.Title == "" && input.Author == "" && input.Price == 0 && input.AuthorIDs == nil && input.GenreIDs == nil {
+		respond.Problem(w, r, problem.New(http.StatusUnprocessableEntity, "The request body has no fields to update."))
 		return
 	}
-	if len(newBook.Author) > 100 {
-		http.Error(w, `{"message": "Author must be 1-100 characters"}`, http.StatusBadRequest)
+
+	updatedData := models.Book{
+		Title:     input.Title,
+		Author:    input.Author,
+		Price:     input.Price,
+		AuthorIDs: input.AuthorIDs,
+		GenreIDs:  input.GenreIDs,
+	}
+	updatedBook, err := data.UpdateBook(id, updatedData, int(expectedVersion))
+	if err != nil {
+		bookWriteError(w, r, err)
 		return
 	}
 
-	book := data.AddBook(newBook)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(book)
+	indexBook(*updatedBook)
+	w.Header().Set("ETag", etag(int64(updatedBook.Version)))
+	respond.Write(w, r, http.StatusOK, map[string]any{
+		"status":  "success",
+		"message": "book updated successfully",
+		"book":    updatedBook,
+	})
 }
 
-// UpdateBook updates an existing book by ID.
-func UpdateBook(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// PatchBook applies a JSON Merge Patch (RFC 7396) to an existing book.
+// Unlike UpdateBook, fields can be cleared with null and price can be set to 0.
+// If-Match is honoured: a stale version returns 412 Precondition Failed.
+func PatchBook(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"message": "invalid book ID"}`, http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid book ID."))
 		return
 	}
 
-	var updatedData models.Book
-	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
-		http.Error(w, `{"message": "invalid JSON"}`, http.StatusBadRequest)
+	if !isMergePatch(r) {
+		respond.Problem(w, r, errNotMergePatch)
 		return
 	}
 
-	// Trim whitespace
-	updatedData.Title = strings.TrimSpace(updatedData.Title)
-	updatedData.Author = strings.TrimSpace(updatedData.Author)
+	current, err := data.GetBookByID(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	expectedVersion, ok := ifMatchVersion(r, int64(current.Version))
+	if !ok {
+		respond.Problem(w, r, errIfMatchFailed)
+		return
+	}
+	if expectedVersion == 0 {
+		expectedVersion = int64(current.Version) // still guard against writes between read and replace
+	}
 
-	// Validate at least one field
-	if updatedData.Title == "" && updatedData.Author == "" {
-		http.Error(w, `{"message": "No fields to update"}`, http.StatusBadRequest)
+	patch, ok := binding.ReadBody(w, r)
+	if !ok {
 		return
 	}
-	// Validate lengths if provided
-	if updatedData.Title != "" && len(updatedData.Title) > 200 {
-		http.Error(w, `{"message": "Title must be 1-200 characters"}`, http.StatusBadRequest)
+
+	original, _ := json.Marshal(current)
+	merged, err := applyMergePatch(original, patch)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "The patch is not valid JSON."))
 		return
 	}
-	if updatedData.Author != "" && len(updatedData.Author) > 100 {
-		http.Error(w, `{"message": "Author must be 1-100 characters"}`, http.StatusBadRequest)
+
+	// Validated against the validate tags of models.Book, so the author can be cleared
+	var patched models.Book
+	if !binding.BindJSON(w, r, merged, &patched) {
 		return
 	}
 
-	updatedBook, err := data.UpdateBook(id, updatedData)
+	book, err := data.ReplaceBook(id, patched, int(expectedVersion))
 	if err != nil {
-		http.Error(w, `{"message": "book not found"}`, http.StatusNotFound)
+		bookWriteError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]any{
-		"status":  "success",
-		"message": "book updated successfully",
-		"book":    updatedBook,
-	})
+	indexBook(*book)
+	w.Header().Set("ETag", etag(int64(book.Version)))
+	respond.Write(w, r, http.StatusOK, book)
 }
 
 // DeleteBook removes a book by ID.
 func DeleteBook(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"message": "invalid book ID"}`, http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid book ID."))
 		return
 	}
 
 	if err := data.DeleteBook(id); err != nil {
-		http.Error(w, `{"message": "book not found"}`, http.StatusNotFound)
+		respond.Error(w, r, err)
 		return
 	}
+	searchIndex.Remove("book", strconv.Itoa(id))
 
-	json.NewEncoder(w).Encode(map[string]string{
+	respond.Write(w, r, http.StatusOK, map[string]string{
 		"status":  "success",
 		"message": "book deleted successfully",
 	})
 }
+
+// bookWriteError responds to a failed create or update of a book. Unknown authors and
+// genres are a mistake in the body rather than a missing resource.
+func bookWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case data.ErrVersionConflict:
+		respond.Problem(w, r, problem.New(http.StatusPreconditionFailed, "The book was modified by another request."))
+	case data.ErrAuthorNotFound, data.ErrGenreNotFound:
+		respond.Problem(w, r, errUnknownBookLinks)
+	default:
+		respond.Error(w, r, err)
+	}
+}
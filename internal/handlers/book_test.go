@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBooksMsgPack(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/books", GetBooks)
+	r.Post("/books", PostBook)
+	r.Get("/books/{id}", GetBookByID)
+
+	var viaJSON, viaMsgPack []models.Book
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/books", nil), respond.JSON, &viaJSON)
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/books", nil), respond.MsgPack, &viaMsgPack)
+	if len(viaJSON) == 0 || !reflect.DeepEqual(viaJSON, viaMsgPack) {
+		t.Errorf("MessagePack %+v differs from JSON %+v", viaMsgPack, viaJSON)
+	}
+
+	// The author required by POST /books is found in a MessagePack body too
+	body := map[string]any{"title": " Kind of Blue ", "author": "Miles Davis", "price": 19.99, "genre_ids": []int{1}}
+	var created models.Book
+	w := fetchAs(t, r, msgPackRequest(t, http.MethodPost, "/books", body), respond.MsgPack, &created)
+	if w.Code != http.StatusCreated || created.Title != "Kind of Blue" || created.Author != "Miles Davis" || len(created.AuthorIDs) != 1 {
+		t.Fatalf("unexpected book %d %+v", w.Code, created)
+	}
+
+	var fetched models.Book
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/books/"+strconv.Itoa(created.ID), nil), respond.JSON, &fetched)
+	if !reflect.DeepEqual(fetched, created) {
+		t.Errorf("stored %+v, MessagePack response was %+v", fetched, created)
+	}
+}
+
+func TestBookWrites(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/books", PostBook)
+	r.Put("/books/{id}", UpdateBook)
+	send := func(method, target, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Accept", respond.JSON)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(http.MethodPost, "/books", `{"title": "Giant Steps", "author": "John Coltrane", "price": 12, "genre_ids": [999]}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown genre, got %d: %s", w.Code, w.Body)
+	}
+
+	// PUT without If-Match replaces whatever version is current
+	w := send(http.MethodPut, "/books/2", `{"price": 18.99}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	version := w.Header().Get("ETag")
+	if w := send(http.MethodPut, "/books/2", `{"price": 19.99}`, `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale If-Match, got %d", w.Code)
+	}
+	if w := send(http.MethodPut, "/books/2", `{"price": 19.99}`, version); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for the current If-Match, got %d: %s", w.Code, w.Body)
+	}
+}
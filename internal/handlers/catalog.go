@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetAuthors returns all authors in JSON format.
+func GetAuthors(w http.ResponseWriter, r *http.Request) {
+	respond.WriteJSON(w, http.StatusOK, data.GetAllAuthors())
+}
+
+// CreateAuthor adds a new author (or returns the existing one with the same name).
+func CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeCatalogName(w, r)
+	if !ok {
+		return
+	}
+
+	respond.WriteJSON(w, http.StatusCreated, data.AddAuthor(name))
+}
+
+// GetBooksByAuthor returns all books written (or co-written) by an author.
+func GetBooksByAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid author ID."))
+		return
+	}
+
+	books, err := data.BooksByAuthor(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	respond.WriteJSON(w, http.StatusOK, books)
+}
+
+// GetGenres returns all genres in JSON format.
+func GetGenres(w http.ResponseWriter, r *http.Request) {
+	respond.WriteJSON(w, http.StatusOK, data.GetAllGenres())
+}
+
+// CreateGenre adds a new genre (or returns the existing one with the same name).
+func CreateGenre(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeCatalogName(w, r)
+	if !ok {
+		return
+	}
+
+	respond.WriteJSON(w, http.StatusCreated, data.AddGenre(name))
+}
+
+// GetBooksByGenre returns all books tagged with a genre.
+func GetBooksByGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid genre ID."))
+		return
+	}
+
+	books, err := data.BooksByGenre(id)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	respond.WriteJSON(w, http.StatusOK, books)
+}
+
+// decodeCatalogName reads {"name": "..."} from the body and validates it.
+// On failure it writes the error response and returns false.
+func decodeCatalogName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var input struct {
+		Name string `json:"name" validate:"trim,required,max=100"`
+	}
+	if !binding.Bind(w, r, &input) {
+		return "", false
+	}
+	return input.Name, true
+}
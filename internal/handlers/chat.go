@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/hub"
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// chatReplay is how many of a room's latest messages a client gets when it joins.
+const chatReplay = 50
+
+// maxRoomMessages is the largest page GET /rooms/{id}/messages returns.
+const maxRoomMessages = 100
+
+// chatQueryTimeout bounds the database calls made for the hub.
+const chatQueryTimeout = 5 * time.Second
+
+// chatHistory stores the hub's room messages in the messages table.
+type chatHistory struct{}
+
+// Save stores a room broadcast.
+func (chatHistory) Save(msg hub.Message) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chatQueryTimeout)
+	defer cancel()
+	m := models.Message{Room: msg.Room, ClientID: msg.From, Data: string(msg.Data), CreatedAt: msg.Time}
+	if msg.User != nil {
+		m.UserID, m.Username = msg.User.ID, msg.User.Name
+	}
+	return data.SaveMessage(ctx, m)
+}
+
+// Recent returns a room's latest n messages.
+func (chatHistory) Recent(room string, n int) ([]hub.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chatQueryTimeout)
+	defer cancel()
+	stored, err := data.RoomMessages(ctx, room, 0, n)
+	if err != nil {
+		return nil, err
+	}
+	return chatMessages(stored), nil
+}
+
+// chatMessages converts stored messages back into the broadcasts they were.
+func chatMessages(stored []models.Message) []hub.Message {
+	msgs := make([]hub.Message, len(stored))
+	for i, m := range stored {
+		msgs[i] = hub.Message{
+			ID:   m.ID,
+			Type: hub.TypeBroadcast,
+			Room: m.Room,
+			From: m.ClientID,
+			User: &hub.User{ID: m.UserID, Name: m.Username},
+			Data: json.RawMessage(m.Data),
+			Time: m.CreatedAt.UTC(),
+		}
+	}
+	return msgs
+}
+
+// InitChatHistory makes the hub persist room messages and replay them on
+// join, and deletes old ones as retention says until ctx is done.
+// Call it once, at startup, after InitWebSocket and the schema has run.
+func InitChatHistory(ctx context.Context, retention data.MessageRetention) {
+	wsHub.SetHistory(chatHistory{}, chatReplay)
+	go data.CleanUpMessages(ctx, retention)
+}
+
+// RoomMessages returns a page of a room's message history as JSON, oldest first:
+// the latest messages, or with ?before={id} the ones before that message.
+// limit defaults to 50 (at most 100). next_before, when present, fetches the
+// previous page. Authentication works as for /ws.
+func RoomMessages(w http.ResponseWriter, r *http.Request) {
+	if _, ok := webSocketUser(r); !ok {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
+	}
+	room := chi.URLParam(r, "id")
+	if !hub.ValidRoom(room) {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid room name."))
+		return
+	}
+
+	var before int64
+	if s := r.URL.Query().Get("before"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "The before parameter must be a message ID."))
+			return
+		}
+		before = id
+	}
+	limit := chatReplay
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxRoomMessages {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "The limit parameter must be between 1 and "+strconv.Itoa(maxRoomMessages)+"."))
+			return
+		}
+		limit = n
+	}
+
+	stored, err := data.RoomMessages(r.Context(), room, before, limit)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	resp := map[string]any{"room": room, "messages": chatMessages(stored)}
+	if len(stored) == limit {
+		resp["next_before"] = stored[0].ID
+	}
+	respond.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/hub"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRoomMessages(t *testing.T) {
+	db := setupWebSocketAuth(t)
+	_, err := db.Exec(`CREATE TABLE messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, room TEXT NOT NULL, client_id TEXT NOT NULL,
+		user_id INTEGER NOT NULL, username TEXT NOT NULL, data TEXT NOT NULL, created_at DATETIME NOT NULL
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		_, err := chatHistory{}.Save(hub.Message{
+			Room: "lobby", From: "c1", User: &hub.User{ID: 1, Name: "gopher"},
+			Data: json.RawMessage(fmt.Sprintf(`"hello %d"`, i)), Time: time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/rooms/{id}/messages", RoomMessages)
+	token, _ := newWebSocketToken(1, time.Now().Add(time.Minute))
+	get := func(url string) (int, map[string]json.RawMessage) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var body map[string]json.RawMessage
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	status, body := get("/rooms/lobby/messages?limit=2")
+	var msgs []hub.Message
+	json.Unmarshal(body["messages"], &msgs)
+	if status != http.StatusOK || len(msgs) != 2 || string(msgs[1].Data) != `"hello 3"` || msgs[1].User.Name != "gopher" {
+		t.Fatalf("latest page: %d %s", status, body["messages"])
+	}
+	if string(body["next_before"]) != fmt.Sprint(msgs[0].ID) {
+		t.Errorf("next_before = %s, want %d", body["next_before"], msgs[0].ID)
+	}
+
+	_, body = get("/rooms/lobby/messages?limit=2&before=" + string(body["next_before"]))
+	json.Unmarshal(body["messages"], &msgs)
+	if len(msgs) != 1 || string(msgs[0].Data) != `"hello 1"` || body["next_before"] != nil {
+		t.Errorf("last page: %s", body)
+	}
+
+	for _, url := range []string{"/rooms/no%20spaces/messages", "/rooms/lobby/messages?before=x", "/rooms/lobby/messages?limit=1000"} {
+		if status, _ := get(url); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, status)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/rooms/lobby/messages", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", rec.Code)
+	}
+}
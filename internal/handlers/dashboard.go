@@ -11,14 +11,7 @@ import (
 func Dashboard(w http.ResponseWriter, r *http.Request) {
 	// Dashboard handler → shows tasks if user logged in, else 401 + login page.
 	if !isAuthenticated(r) {
-		session, _ := store.Get(r, "session")
-		session.Values["redirect_after_login"] = "/dashboard"
-		session.Save(r, w)
-
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Header().Set("Content-Type", "text/html")
-		tmpl := template.Must(template.ParseFiles("templates/login_required.html"))
-		tmpl.Execute(w, map[string]string{"Resource": "dashboard"})
+		loginRequired(w, r, "dashboard", "/dashboard")
 		return
 	}
 
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/events"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+)
+
+// eventReplaySize is how many recent events GET /events can replay to a
+// reconnecting client.
+const eventReplaySize = 1000
+
+// eventQueueSize is how many events may wait for a slow /events client before
+// its stream is ended; it reconnects and catches up from the replay buffer.
+const eventQueueSize = 64
+
+// eventHeartbeat is how often /events writes a comment to an idle stream, so
+// proxies don't time it out and dead clients are noticed. Shortened in tests.
+var eventHeartbeat = 15 * time.Second
+
+// eventReplay holds the recent events of the default bus for Last-Event-ID resumes.
+var eventReplay = events.NewReplay(eventReplaySize)
+
+// InitEventStream starts recording the data layer's events for GET /events to replay.
+// Call it once, at startup.
+func InitEventStream() {
+	events.Subscribe("*", eventReplay.Add)
+}
+
+// EventStream delivers the events the WebSocket hub publishes on its topics as
+// server-sent events, for clients whose proxies break WebSocket upgrades:
+//
+//	id: 42
+//	event: stock.changed
+//	data: {"id":42,"topic":"album:1","type":"stock.changed","data":{...},"time":...}
+//
+// Topics are chosen with repeated topic parameters (albums, album:{id},
+// orders:me) and authentication works as for /ws. A reconnecting EventSource
+// sends Last-Event-ID and gets the events it missed from the replay buffer;
+// if some are no longer there, a reset event tells it to reload instead.
+// Idle streams get a heartbeat comment every 15 seconds.
+func EventStream(w http.ResponseWriter, r *http.Request) {
+	user, ok := webSocketUser(r)
+	if !ok {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
+	}
+	topics := map[string]bool{}
+	for _, topic := range r.URL.Query()["topic"] {
+		resolved, err := webSocketTopic(user, topic)
+		if err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+			return
+		}
+		topics[resolved] = true
+	}
+	if len(topics) == 0 {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "At least one topic parameter is required."))
+		return
+	}
+	var lastID uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid Last-Event-ID."))
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before reading the replay buffer so nothing falls in between;
+	// events seen in both are skipped by ID below
+	queue := make(chan events.Event, eventQueueSize)
+	overflow := make(chan struct{})
+	var once sync.Once
+	cancel := events.Subscribe("*", func(e events.Event) {
+		if !topics[e.Topic] {
+			return
+		}
+		select {
+		case queue <- e:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	})
+	defer cancel()
+
+	// The middleware (Logger, Tracing) may wrap w; the ResponseController
+	// reaches the server's writer through their Unwrap methods
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // the stream outlives any server WriteTimeout
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let proxies buffer the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	sent := lastID
+	send := func(e events.Event) error {
+		if e.ID <= sent {
+			return nil
+		}
+		sent = e.ID
+		if err := writeEvent(w, e); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if lastID > 0 {
+		missed, complete := eventReplay.Since(lastID)
+		if !complete {
+			// Some events are gone; the client has to start over from the current state
+			sent = eventReplay.LastID()
+			fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", sent)
+			missed = nil
+		}
+		for _, e := range missed {
+			if topics[e.Topic] {
+				if err := send(e); err != nil {
+					return
+				}
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e := <-queue:
+			if err := send(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-overflow:
+			return // too slow; the client reconnects with Last-Event-ID
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes e in the event stream format.
+func writeEvent(w io.Writer, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
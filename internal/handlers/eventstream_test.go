@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/events"
+	"github.com/shahinzaman102/Go_JumpStart/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// sseBlock is one blank-line terminated block of an event stream.
+type sseBlock map[string]string
+
+// openEventStream connects to /events (behind the same middleware as the real
+// router) and returns the response status and a channel of its blocks.
+func openEventStream(t *testing.T, url, lastID string) (int, <-chan sseBlock) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	token, _ := newWebSocketToken(1, time.Now().Add(time.Minute))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	blocks := make(chan sseBlock, 16)
+	go func() {
+		defer close(blocks)
+		scanner := bufio.NewScanner(resp.Body)
+		block := sseBlock{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+				block = sseBlock{}
+				continue
+			}
+			field, value, _ := strings.Cut(line, ":")
+			block[field] = strings.TrimPrefix(value, " ")
+		}
+	}()
+	return resp.StatusCode, blocks
+}
+
+// nextEvent returns the next block that isn't a heartbeat or retry hint.
+func nextEvent(t *testing.T, blocks <-chan sseBlock) sseBlock {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case b, ok := <-blocks:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			if b["event"] != "" {
+				return b
+			}
+		case <-timeout:
+			t.Fatal("no event within 5s")
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	setupWebSocketAuth(t)
+	eventReplay = events.NewReplay(3)
+	t.Cleanup(events.Subscribe("*", eventReplay.Add))
+	heartbeat := eventHeartbeat
+	eventHeartbeat = 20 * time.Millisecond
+	t.Cleanup(func() { eventHeartbeat = heartbeat })
+
+	r := chi.NewRouter()
+	r.Use(chimiddleware.Logger)
+	r.Use(middleware.Tracing)
+	r.Get("/events", EventStream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close) // runs after the streams' cleanups have disconnected them
+	url := srv.URL + "/events?topic=album:1&topic=orders:me"
+
+	if status, _ := openEventStream(t, srv.URL+"/events?topic=orders:user:2", ""); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for someone else's orders, got %d", status)
+	}
+
+	status, blocks := openEventStream(t, url, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	first := <-blocks // the retry hint, flushed straight away
+	if first["retry"] == "" {
+		t.Fatalf("expected a retry hint first, got %v", first)
+	}
+	waitForHeartbeat := func() {
+		for b := range blocks {
+			if _, ok := b[""]; ok {
+				return
+			}
+		}
+	}
+	waitForHeartbeat() // the handler has subscribed by now
+
+	events.Publish("album:1", events.StockChanged, events.Stock{AlbumID: 1, Quantity: 4})
+	events.Publish("album:2", events.StockChanged, events.Stock{AlbumID: 2}) // not subscribed
+	events.Publish("orders:user:1", events.OrderCreated, map[string]int{"id": 9})
+
+	stock := nextEvent(t, blocks)
+	if stock["event"] != events.StockChanged || !strings.Contains(stock["data"], `"quantity":4`) {
+		t.Errorf("unexpected stock event %v", stock)
+	}
+	order := nextEvent(t, blocks)
+	if order["event"] != events.OrderCreated || !strings.Contains(order["data"], `"topic":"orders:user:1"`) {
+		t.Errorf("unexpected order event %v", order)
+	}
+
+	// Resuming after the stock event replays the order only
+	_, resumed := openEventStream(t, url, stock["id"])
+	if e := nextEvent(t, resumed); e["id"] != order["id"] {
+		t.Errorf("expected the order to be replayed, got %v", e)
+	}
+
+	// Resuming from an evicted event asks the client to reset
+	for range 3 {
+		events.Publish("album:2", events.StockChanged, events.Stock{AlbumID: 2})
+	}
+	_, reset := openEventStream(t, url, stock["id"])
+	e := nextEvent(t, reset)
+	if e["event"] != "reset" || e["id"] != strconv.FormatUint(eventReplay.LastID(), 10) {
+		t.Errorf("expected a reset to the latest ID, got %v", e)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 )
 
 // GoBasics demonstrates core Go features through HTTP output.
@@ -105,7 +108,7 @@ func GoBasics(w http.ResponseWriter, r *http.Request) {
 	if exitVal := r.URL.Query().Get("exit"); exitVal != "" {
 		code, err := strconv.Atoi(exitVal)
 		if err != nil {
-			http.Error(w, "Invalid exit code", http.StatusBadRequest)
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid exit code."))
 			return
 		}
 
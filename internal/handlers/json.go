@@ -1,47 +1,44 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 )
 
-// JsonEncode: Take JSON -> struct -> return JSON
+// JsonEncode: Take JSON (or MessagePack) -> struct -> return JSON (or MessagePack, if the Accept header asks for it)
 func JsonEncode(w http.ResponseWriter, r *http.Request) {
 	var u models.User
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if !binding.Bind(w, r, &u) { // decodes by Content-Type
 		return
 	}
 
 	// Validate required fields
 	if u.Username == "" || u.Password == "" {
-		http.Error(w, "Username and Password are required", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Username and Password are required."))
 		return
 	}
 
 	u.CreatedAt = time.Now()
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(u); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-	}
+	respond.Write(w, r, http.StatusOK, u)
 }
 
-// JsonDecode: Take JSON -> struct -> return text summary
+// JsonDecode: Take JSON (or MessagePack) -> struct -> return text summary
 func JsonDecode(w http.ResponseWriter, r *http.Request) {
 	var u models.User
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if !binding.Bind(w, r, &u) {
 		return
 	}
 
 	// Validate required fields
 	if u.Username == "" || u.Password == "" {
-		http.Error(w, "Username and Password are required", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Username and Password are required."))
 		return
 	}
 
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+)
+
+// mergePatchContentType is the media type for JSON Merge Patch (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
+// errIfMatchFailed is the response to a write whose If-Match names none of the current version.
+var errIfMatchFailed = problem.New(http.StatusPreconditionFailed, "The resource has changed since the version given in If-Match.")
+
+// errNotMergePatch is the response to a PATCH whose body isn't declared as a merge patch.
+var errNotMergePatch = problem.New(http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType+".")
+
+// isMergePatch reports whether the request body is declared as a JSON Merge Patch.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == mergePatchContentType
+}
+
+// applyMergePatch applies a JSON Merge Patch document to the original JSON document.
+// Object members set to null are removed; any other value replaces the original.
+func applyMergePatch(original, patch []byte) ([]byte, error) {
+	var doc, p any
+	if err := json.Unmarshal(original, &doc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(doc, p))
+}
+
+// mergeValue implements the recursive MergePatch algorithm from RFC 7396.
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch // non-object patches replace the target entirely
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// etag formats a resource version as a strong entity tag, e.g. "3".
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion checks the If-Match header against a resource's current version.
+// It returns the version the write must still find: current if the header
+// lists it, or 0 when the header is absent or "*". ok is false if no entry
+// names current; entries that aren't version tags can never match, so they
+// fail the precondition too rather than the request. Weak tags (W/"3") count
+// as well: proxies weaken ETags when they re-encode responses, and a version
+// names the same content either way.
+func ifMatchVersion(r *http.Request, current int64) (version int64, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.ParseInt(unquoted, 10, 64); err == nil && v == current {
+			return current, true
+		}
+	}
+	return 0, false
+}
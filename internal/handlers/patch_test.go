@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+	}{
+		{"replace field", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add field", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"zero is kept", `{"price":9.5}`, `{"price":0}`, `{"price":0}`},
+		{"nested merge", `{"a":{"b":"c","d":"e"}}`, `{"a":{"d":null,"f":"g"}}`, `{"a":{"b":"c","f":"g"}}`},
+		{"array replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMergePatch([]byte(tt.original), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var gotVal, wantVal any
+			json.Unmarshal(got, &gotVal)
+			json.Unmarshal([]byte(tt.want), &wantVal)
+			if !reflect.DeepEqual(gotVal, wantVal) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,32 +1,41 @@
 package handlers
 
 import (
-	"encoding/json"
-	"math"
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
 	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder/maze"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 )
 
 // PathfinderResponse represents the result of a pathfinding algorithm.
 type PathfinderResponse struct {
-	Algorithm     string  `json:"algorithm"`
-	PathLength    int     `json:"path_length"`
-	ExecutionTime float64 `json:"execution_time_ms"`
+	Algorithm     string             `json:"algorithm"`
+	PathLength    int                `json:"path_length"`
+	ExecutionTime float64            `json:"execution_time_ms"`
+	Path          []pathfinder.Point `json:"path"`
+	Cost          float64            `json:"cost"`
+	NodesExpanded int                `json:"nodes_expanded"`
+	Error         string             `json:"error,omitempty"` // set when an algorithm gave up
 }
 
-// Pathfinder runs pathfinding algorithms (Brute Force and BFS)
-// on a sample grid and returns their performance as JSON.
-// Query parameters: start=x,y and end=x,y
+// Pathfinder runs the pathfinding algorithms (Brute Force, BFS, Dijkstra and A*)
+// on a sample grid and returns their paths and performance as JSON.
+// Query parameters: start=x,y and end=x,y; optional diagonal=true,
+// corners=never|no-squeeze|always and heuristic=manhattan|euclidean|octile
 func Pathfinder(w http.ResponseWriter, r *http.Request) {
 	startParam := r.URL.Query().Get("start")
 	endParam := r.URL.Query().Get("end")
 
 	if startParam == "" || endParam == "" {
-		http.Error(w, "Missing required params: start, end", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Missing required params: start, end."))
 		return
 	}
 
@@ -34,29 +43,35 @@ func Pathfinder(w http.ResponseWriter, r *http.Request) {
 	endCoords := strings.Split(endParam, ",")
 
 	if len(startCoords) != 2 || len(endCoords) != 2 {
-		http.Error(w, "Invalid coordinates format. Use start=x,y end=x,y", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid coordinates format. Use start=x,y end=x,y."))
 		return
 	}
 
 	startX, err := strconv.Atoi(startCoords[0])
 	if err != nil {
-		http.Error(w, "Invalid start X coordinate", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid start X coordinate."))
 		return
 	}
 	startY, err := strconv.Atoi(startCoords[1])
 	if err != nil {
-		http.Error(w, "Invalid start Y coordinate", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid start Y coordinate."))
 		return
 	}
 
 	destX, err := strconv.Atoi(endCoords[0])
 	if err != nil {
-		http.Error(w, "Invalid destination X coordinate", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid destination X coordinate."))
 		return
 	}
 	destY, err := strconv.Atoi(endCoords[1])
 	if err != nil {
-		http.Error(w, "Invalid destination Y coordinate", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid destination Y coordinate."))
+		return
+	}
+
+	opts, err := pathfinderOptions(r)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
@@ -77,68 +92,309 @@ func Pathfinder(w http.ResponseWriter, r *http.Request) {
 
 	// Validate bounds
 	if startX < 0 || startY < 0 || startX >= rows || startY >= cols {
-		http.Error(w, "Invalid start coordinates: out of bounds", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid start coordinates: out of bounds."))
 		return
 	}
 	if destX < 0 || destY < 0 || destX >= rows || destY >= cols {
-		http.Error(w, "Invalid destination coordinates: out of bounds", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid destination coordinates: out of bounds."))
 		return
 	}
 
 	// Validate obstacles
 	if grid[startX][startY] == 1 {
-		http.Error(w, "Invalid start coordinates: cannot be on an obstacle", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid start coordinates: cannot be on an obstacle."))
 		return
 	}
 	if grid[destX][destY] == 1 {
-		http.Error(w, "Invalid destination coordinates: cannot be on an obstacle", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid destination coordinates: cannot be on an obstacle."))
 		return
 	}
 
-	// const runs = 1
-	const runs = 1000
+	start, dest := pathfinder.Point{X: startX, Y: startY}, pathfinder.Point{X: destX, Y: destY}
+	results, err := runPathfinders(r.Context(), grid, start, dest, opts, pathfinder.AlgorithmNames)
+	if ctxErr := r.Context().Err(); ctxErr != nil && err == ctxErr {
+		return // the client has gone
+	}
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	respond.Write(w, r, http.StatusOK, results)
+}
+
+// maxBruteForceCells is the largest grid (rows × cols) brute force is run on;
+// beyond about 8×8 it can take minutes. Larger grids get an error result instead.
+const maxBruteForceCells = 64
+
+// runPathfinders runs and times each named algorithm once on the grid,
+// stopping with ctx's error if it is cancelled between them. Algorithms that
+// refuse or give up get a result with Error set; other errors (such as
+// invalid points) are returned.
+func runPathfinders(ctx context.Context, grid [][]int, start, dest pathfinder.Point, opts pathfinder.Options, names []string) ([]PathfinderResponse, error) {
 	results := []PathfinderResponse{}
 
-	// Brute Force
-	var brutePathLen int
-	startTime := time.Now()
-	for i := 0; i < runs; i++ {
-		visited := make([][]bool, len(grid))
-		for r := range visited {
-			visited[r] = make([]bool, len(grid[0]))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if name == "brute" && len(grid)*len(grid[0]) > maxBruteForceCells {
+			results = append(results, PathfinderResponse{
+				Algorithm:  name,
+				PathLength: -1,
+				Error:      fmt.Sprintf("brute force is refused for grids over %d cells", maxBruteForceCells),
+			})
+			continue
+		}
+		search := pathfinder.Algorithms[name]
+
+		startTime := time.Now()
+		res, err := search(grid, start, dest, opts)
+		duration := time.Since(startTime).Seconds() * 1000
+
+		if err == pathfinder.ErrSearchLimit {
+			results = append(results, PathfinderResponse{Algorithm: name, PathLength: -1, Error: err.Error()})
+			continue
 		}
-		path := pathfinder.ShortestPathBruteForce(grid, startX, startY, destX, destY, visited)
-		if path == math.MaxInt32 {
-			path = -1
+		if err != nil {
+			return nil, err
 		}
-		brutePathLen = path
+		results = append(results, PathfinderResponse{
+			Algorithm:     name,
+			PathLength:    res.Length,
+			ExecutionTime: duration,
+			Path:          res.Path,
+			Cost:          res.Cost,
+			NodesExpanded: res.NodesExpanded,
+		})
 	}
-	bruteDuration := time.Since(startTime).Seconds() * 1000 / runs
+	return results, nil
+}
 
-	results = append(results, PathfinderResponse{
-		Algorithm:     "brute",
-		PathLength:    brutePathLen,
-		ExecutionTime: bruteDuration,
-	})
+// PathfinderGrid is the grid of a POST /pathfinder request, given either as a
+// JSON matrix (0 = free, 1 = wall, 2-9 = weighted terrain) or as ASCII art (. # S E 2-9).
+type PathfinderGrid struct {
+	Grid  [][]int `json:"grid"`
+	ASCII string  `json:"ascii"`
+}
+
+// parse validates the grid and returns the S and E cells of ASCII art (nil for a matrix).
+func (g PathfinderGrid) parse() ([][]int, *pathfinder.Point, *pathfinder.Point, error) {
+	switch {
+	case g.Grid != nil && g.ASCII != "":
+		return nil, nil, nil, fmt.Errorf("give either grid or ascii, not both")
+	case g.ASCII != "":
+		return pathfinder.ParseASCII(g.ASCII)
+	case g.Grid != nil:
+		return g.Grid, nil, nil, pathfinder.ValidateGrid(g.Grid)
+	default:
+		return nil, nil, nil, fmt.Errorf("missing grid or ascii")
+	}
+}
+
+// PathfinderOptions are the movement options of a POST /pathfinder request.
+type PathfinderOptions struct {
+	Diagonal  bool   `json:"diagonal"`
+	Corners   string `json:"corners"`
+	Heuristic string `json:"heuristic"`
+}
+
+// options validates the options.
+func (o PathfinderOptions) options() (pathfinder.Options, error) {
+	opts := pathfinder.Options{Diagonal: o.Diagonal}
+	var err error
+	if opts.Corners, err = pathfinder.ParseCornerRule(o.Corners); err != nil {
+		return opts, err
+	}
+	opts.Heuristic, err = pathfinder.ParseHeuristic(o.Heuristic)
+	return opts, err
+}
 
-	// BFS
-	var bfsPathLen int
-	startTime = time.Now()
-	for i := 0; i < runs; i++ {
-		bfsPathLen = pathfinder.ShortestPathBFS(grid, startX, startY, destX, destY)
+// PathfinderRequest is the body of POST /pathfinder.
+// Start/End are required with a matrix and override S/E in ASCII art.
+type PathfinderRequest struct {
+	PathfinderGrid
+	PathfinderOptions
+	Start     *pathfinder.Point `json:"start"`
+	End       *pathfinder.Point `json:"end"`
+	Algorithm string            `json:"algorithm"` // brute, bfs, dijkstra, astar or all (default)
+}
+
+// PathfinderSolution is the response of POST /pathfinder.
+type PathfinderSolution struct {
+	Rows    int                  `json:"rows"`
+	Cols    int                  `json:"cols"`
+	Start   pathfinder.Point     `json:"start"`
+	End     pathfinder.Point     `json:"end"`
+	Results []PathfinderResponse `json:"results"`
+}
+
+// SolvePathfinder handles POST /pathfinder
+// Runs one or all algorithms on a user-supplied grid
+func SolvePathfinder(w http.ResponseWriter, r *http.Request) {
+	var req PathfinderRequest
+	if !binding.Bind(w, r, &req) {
+		return
 	}
-	bfsDuration := time.Since(startTime).Seconds() * 1000 / runs
 
-	results = append(results, PathfinderResponse{
-		Algorithm:     "bfs",
-		PathLength:    bfsPathLen,
-		ExecutionTime: bfsDuration,
+	grid, start, end, err := pathfinderGrid(req)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	names := pathfinder.AlgorithmNames
+	if req.Algorithm != "" && req.Algorithm != "all" {
+		if _, ok := pathfinder.Algorithms[req.Algorithm]; !ok {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, fmt.Sprintf("Unknown algorithm %q (use %s or all).",
+				req.Algorithm, strings.Join(pathfinder.AlgorithmNames, ", "))))
+			return
+		}
+		if req.Algorithm == "brute" && len(grid)*len(grid[0]) > maxBruteForceCells {
+			respond.Problem(w, r, problem.New(http.StatusUnprocessableEntity,
+				fmt.Sprintf("Brute force is refused for grids over %d cells.", maxBruteForceCells)))
+			return
+		}
+		names = []string{req.Algorithm}
+	}
+
+	opts, err := req.options()
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	results, err := runPathfinders(r.Context(), grid, start, end, opts, names)
+	if ctxErr := r.Context().Err(); ctxErr != nil && err == ctxErr {
+		return // the client has gone
+	}
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid start or end: "+err.Error()))
+		return
+	}
+
+	respond.Write(w, r, http.StatusOK, PathfinderSolution{
+		Rows:    len(grid),
+		Cols:    len(grid[0]),
+		Start:   start,
+		End:     end,
+		Results: results,
 	})
+}
 
-	// Write JSON response
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(results); err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+// pathfinderGrid extracts and validates the grid, start and end from a request.
+func pathfinderGrid(req PathfinderRequest) ([][]int, pathfinder.Point, pathfinder.Point, error) {
+	var none pathfinder.Point
+	grid, start, end, err := req.parse()
+	if err != nil {
+		return nil, none, none, err
+	}
+	if req.Start != nil {
+		start = req.Start
+	}
+	if req.End != nil {
+		end = req.End
+	}
+	if start == nil || end == nil {
+		return nil, none, none, fmt.Errorf("missing start or end")
+	}
+	return grid, *start, *end, nil
+}
+
+// GeneratedGrid is the response of GET /pathfinder/generate. ASCII can be
+// posted back to POST /pathfinder as-is.
+type GeneratedGrid struct {
+	Type    string           `json:"type"`
+	Rows    int              `json:"rows"`
+	Cols    int              `json:"cols"`
+	Density float64          `json:"density,omitempty"`
+	Seed    int64            `json:"seed"`
+	Start   pathfinder.Point `json:"start"`
+	End     pathfinder.Point `json:"end"`
+	Grid    [][]int          `json:"grid"`
+	ASCII   string           `json:"ascii"`
+}
+
+// GeneratePathfinderGrid handles GET /pathfinder/generate
+// Query parameters (all optional): type=backtracker|prim|random (default backtracker),
+// rows and cols (default 21), density=0-1 for random grids (default 0.3) and
+// seed (default random; the seed used is returned so the grid can be reproduced)
+func GeneratePathfinderGrid(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	kind := q.Get("type")
+	if kind == "" {
+		kind = maze.Backtracker
+	}
+
+	rows, cols, density, seed := 21, 21, 0.3, time.Now().UnixNano()
+	var err error
+	if v := q.Get("rows"); v != "" {
+		if rows, err = strconv.Atoi(v); err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid rows."))
+			return
+		}
+	}
+	if v := q.Get("cols"); v != "" {
+		if cols, err = strconv.Atoi(v); err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid cols."))
+			return
+		}
+	}
+	if v := q.Get("density"); v != "" {
+		if density, err = strconv.ParseFloat(v, 64); err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid density."))
+			return
+		}
+	}
+	if v := q.Get("seed"); v != "" {
+		if seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid seed."))
+			return
+		}
+	}
+
+	m, err := maze.Generate(kind, rows, cols, density, seed)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
 		return
 	}
+	if kind != maze.Random {
+		density = 0
+	}
+
+	respond.Write(w, r, http.StatusOK, GeneratedGrid{
+		Type:    kind,
+		Rows:    rows,
+		Cols:    cols,
+		Density: density,
+		Seed:    seed,
+		Start:   m.Start,
+		End:     m.End,
+		Grid:    m.Grid,
+		ASCII:   pathfinder.FormatASCII(m.Grid, &m.Start, &m.End),
+	})
+}
+
+// pathfinderOptions reads the movement options from the query string.
+func pathfinderOptions(r *http.Request) (pathfinder.Options, error) {
+	var opts pathfinder.Options
+	q := r.URL.Query()
+
+	if v := q.Get("diagonal"); v != "" {
+		diagonal, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid diagonal value %q", v)
+		}
+		opts.Diagonal = diagonal
+	}
+	corners, err := pathfinder.ParseCornerRule(q.Get("corners"))
+	if err != nil {
+		return opts, err
+	}
+	heuristic, err := pathfinder.ParseHeuristic(q.Get("heuristic"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Corners, opts.Heuristic = corners, heuristic
+	return opts, nil
 }
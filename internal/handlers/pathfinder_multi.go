@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+)
+
+// Limits for the multi-path endpoints.
+const (
+	maxPathQueries  = 1000 // queries or starts per request
+	maxAgents       = 32   // agents per request: each is a space-time search
+	maxBatchWorkers = 16
+)
+
+// AgentPath is the path of one query, start or agent in a multi-path response.
+type AgentPath struct {
+	Start         pathfinder.Point   `json:"start"`
+	End           pathfinder.Point   `json:"end"` // the requested goal, or the nearest target found
+	Found         bool               `json:"found"`
+	PathLength    int                `json:"path_length"`
+	Path          []pathfinder.Point `json:"path"`
+	Cost          float64            `json:"cost"`
+	NodesExpanded int                `json:"nodes_expanded"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// MultiPathResponse is the response of the multi-path endpoints.
+type MultiPathResponse struct {
+	Algorithm     string      `json:"algorithm"`
+	Rows          int         `json:"rows"`
+	Cols          int         `json:"cols"`
+	Workers       int         `json:"workers,omitempty"`
+	ExecutionTime float64     `json:"execution_time_ms"`
+	Paths         []AgentPath `json:"paths"`
+}
+
+// agentPath converts a search result for the response.
+func agentPath(start, end pathfinder.Point, res pathfinder.Result) AgentPath {
+	if res.Found {
+		end = res.Path[len(res.Path)-1]
+	}
+	return AgentPath{
+		Start:         start,
+		End:           end,
+		Found:         res.Found,
+		PathLength:    res.Length,
+		Path:          res.Path,
+		Cost:          res.Cost,
+		NodesExpanded: res.NodesExpanded,
+	}
+}
+
+// multiPathGrid validates the grid and the number of paths asked for (n, at
+// most limit) of a multi-path request. It writes the error response and
+// returns nil if invalid.
+func multiPathGrid(w http.ResponseWriter, r *http.Request, g PathfinderGrid, n, limit int) [][]int {
+	grid, _, _, err := g.parse()
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return nil
+	}
+	if n == 0 || n > limit {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, fmt.Sprintf("Give between 1 and %d paths to find", limit)))
+		return nil
+	}
+	return grid
+}
+
+// writeMultiPath writes a multi-path response.
+func writeMultiPath(w http.ResponseWriter, r *http.Request, resp MultiPathResponse, grid [][]int, started time.Time) {
+	resp.Rows, resp.Cols = len(grid), len(grid[0])
+	resp.ExecutionTime = time.Since(started).Seconds() * 1000
+	respond.Write(w, r, http.StatusOK, resp)
+}
+
+// BatchPathRequest is the body of POST /pathfinder/batch.
+type BatchPathRequest struct {
+	PathfinderGrid
+	PathfinderOptions
+	Queries   []pathfinder.Query `json:"queries"`
+	Algorithm string             `json:"algorithm"` // default astar
+	Workers   int                `json:"workers"`   // default the number of CPUs, at most 16
+}
+
+// BatchPathfinder handles POST /pathfinder/batch
+// Finds the paths of many independent start/end pairs on one grid concurrently,
+// using a bounded pool of workers. Invalid pairs get an error in their entry.
+func BatchPathfinder(w http.ResponseWriter, r *http.Request) {
+	var req BatchPathRequest
+	if !binding.Bind(w, r, &req) {
+		return
+	}
+	grid := multiPathGrid(w, r, req.PathfinderGrid, len(req.Queries), maxPathQueries)
+	if grid == nil {
+		return
+	}
+
+	if req.Algorithm == "" {
+		req.Algorithm = "astar"
+	}
+	search, ok := pathfinder.Algorithms[req.Algorithm]
+	if !ok {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, fmt.Sprintf("Unknown algorithm %q", req.Algorithm)))
+		return
+	}
+	if req.Algorithm == "brute" && len(grid)*len(grid[0]) > maxBruteForceCells {
+		respond.Problem(w, r, problem.New(http.StatusUnprocessableEntity,
+			fmt.Sprintf("Brute force is refused for grids over %d cells.", maxBruteForceCells)))
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	workers := req.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, maxBatchWorkers)
+
+	started := time.Now()
+	results := pathfinder.Batch(r.Context(), grid, req.Queries, search, opts, workers)
+
+	paths := make([]AgentPath, len(results))
+	for i, res := range results {
+		q := req.Queries[i]
+		if res.Err != nil {
+			paths[i] = AgentPath{Start: q.Start, End: q.Goal, PathLength: -1, Error: res.Err.Error()}
+			continue
+		}
+		paths[i] = agentPath(q.Start, q.Goal, res.Result)
+	}
+	writeMultiPath(w, r, MultiPathResponse{Algorithm: req.Algorithm, Workers: workers, Paths: paths}, grid, started)
+}
+
+// NearestPathRequest is the body of POST /pathfinder/nearest.
+type NearestPathRequest struct {
+	PathfinderGrid
+	PathfinderOptions
+	Starts  []pathfinder.Point `json:"starts"`
+	Targets []pathfinder.Point `json:"targets"`
+}
+
+// NearestPathfinder handles POST /pathfinder/nearest
+// Finds, for each start, the fewest-moves path to the closest of the targets
+// with a single multi-source BFS. Each entry's end is the target it reached.
+func NearestPathfinder(w http.ResponseWriter, r *http.Request) {
+	var req NearestPathRequest
+	if !binding.Bind(w, r, &req) {
+		return
+	}
+	grid := multiPathGrid(w, r, req.PathfinderGrid, len(req.Starts), maxPathQueries)
+	if grid == nil {
+		return
+	}
+	if len(req.Targets) > pathfinder.MaxGridSide*pathfinder.MaxGridSide {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Too many targets."))
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	started := time.Now()
+	results, err := pathfinder.NearestTarget(grid, req.Starts, req.Targets, opts)
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid starts or targets: "+err.Error()))
+		return
+	}
+
+	paths := make([]AgentPath, len(results))
+	for i, res := range results {
+		paths[i] = agentPath(req.Starts[i], pathfinder.Point{}, res)
+	}
+	writeMultiPath(w, r, MultiPathResponse{Algorithm: "nearest", Paths: paths}, grid, started)
+}
+
+// AgentsPathRequest is the body of POST /pathfinder/agents.
+type AgentsPathRequest struct {
+	PathfinderGrid
+	PathfinderOptions
+	Agents []pathfinder.Query `json:"agents"`
+}
+
+// AgentsPathfinder handles POST /pathfinder/agents
+// Plans collision-free paths for agents moving at the same time, one step per
+// time unit, by reserving cells in space-time in the order the agents are given.
+// Paths repeat a cell where the agent waits; agents that couldn't be routed
+// around the earlier ones are reported with found=false.
+func AgentsPathfinder(w http.ResponseWriter, r *http.Request) {
+	var req AgentsPathRequest
+	if !binding.Bind(w, r, &req) {
+		return
+	}
+	grid := multiPathGrid(w, r, req.PathfinderGrid, len(req.Agents), maxAgents)
+	if grid == nil {
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	started := time.Now()
+	results, err := pathfinder.Cooperative(r.Context(), grid, req.Agents, opts)
+	if ctxErr := r.Context().Err(); ctxErr != nil && err == ctxErr {
+		return // the client has gone
+	}
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid agents: "+err.Error()))
+		return
+	}
+
+	paths := make([]AgentPath, len(results))
+	for i, res := range results {
+		paths[i] = agentPath(req.Agents[i].Start, req.Agents[i].Goal, res)
+	}
+	writeMultiPath(w, r, MultiPathResponse{Algorithm: "cooperative", Paths: paths}, grid, started)
+}
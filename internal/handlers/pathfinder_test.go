@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+)
+
+func TestRunPathfinders(t *testing.T) {
+	grid := [][]int{
+		{0, 0, 0},
+		{1, 1, 0},
+		{0, 0, 0},
+	}
+	start, end := pathfinder.Point{X: 0, Y: 0}, pathfinder.Point{X: 2, Y: 0}
+
+	results, err := runPathfinders(context.Background(), grid, start, end, pathfinder.Options{}, pathfinder.AlgorithmNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(pathfinder.AlgorithmNames) {
+		t.Fatalf("expected a result per algorithm, got %+v", results)
+	}
+	for _, res := range results {
+		if res.PathLength != 6 || res.Error != "" {
+			t.Errorf("%s: expected a path of 6 moves, got %+v", res.Algorithm, res)
+		}
+	}
+
+	// Nothing more is run once the client has gone
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := runPathfinders(ctx, grid, start, end, pathfinder.Options{}, pathfinder.AlgorithmNames); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSolvePathfinder(t *testing.T) {
+	body := PathfinderRequest{
+		PathfinderGrid: PathfinderGrid{ASCII: "S.#\n..E\n"},
+		Algorithm:      "bfs",
+	}
+	var solution PathfinderSolution
+	fetchAs(t, http.HandlerFunc(SolvePathfinder), msgPackRequest(t, http.MethodPost, "/pathfinder", body), respond.MsgPack, &solution)
+	if solution.Rows != 2 || solution.Cols != 3 || len(solution.Results) != 1 || solution.Results[0].PathLength != 3 {
+		t.Errorf("unexpected solution %+v", solution)
+	}
+
+	for _, body := range []string{
+		`{"ascii": "S.E", "algoritm": "bfs"}`, // misspelt
+		`{"ascii": "S.E"} {"ascii": "S.E"}`,
+	} {
+		w := httptest.NewRecorder()
+		SolvePathfinder(w, httptest.NewRequest(http.MethodPost, "/pathfinder", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestBatchPathfinder(t *testing.T) {
+	var resp MultiPathResponse
+	req := httptest.NewRequest(http.MethodPost, "/pathfinder/batch",
+		strings.NewReader(`{"ascii": "...\n.#.\n...", "queries": [{"start": {"x": 0, "y": 0}, "end": {"x": 2, "y": 2}}]}`))
+	fetchAs(t, http.HandlerFunc(BatchPathfinder), req, respond.JSON, &resp)
+	if len(resp.Paths) != 1 || !resp.Paths[0].Found || resp.Paths[0].PathLength != 4 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	big := `{"ascii": "` + strings.Repeat(".", binding.MaxBodySize) + `"}`
+	w := httptest.NewRecorder()
+	BatchPathfinder(w, httptest.NewRequest(http.MethodPost, "/pathfinder/batch", strings.NewReader(big)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a body over the limit, got %d", w.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	assets "github.com/shahinzaman102/Go_JumpStart"
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+)
+
+// Limits for GET /pathfinder/stream. Each step is one event, so the grid is
+// smaller than POST /pathfinder allows and brute force stops reporting steps
+// after maxStreamSteps (the search itself still finishes).
+const (
+	maxStreamSide  = 64
+	maxStreamSteps = 10000
+	maxStreamDelay = 500 * time.Millisecond
+)
+
+// PathfinderPlayground serves the page for drawing grids and watching the
+// algorithms search them step by step.
+func PathfinderPlayground(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := template.ParseFS(assets.Templates, "templates/pathfinder.html")
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	tmpl.Execute(w, map[string]any{
+		"Algorithms": pathfinder.AlgorithmNames,
+		"MaxSide":    maxStreamSide,
+		"MaxBrute":   maxBruteForceCells,
+	})
+}
+
+// StreamPathfinder handles GET /pathfinder/stream
+// Runs one algorithm and streams its search as server-sent events:
+//
+//	event: visit  data: {"x":..,"y":..}    each node in the order it's expanded
+//	event: done   data: {"algorithm":..}   the PathfinderResponse with the final path
+//	event: fail   data: message            the request was invalid or the search gave up
+//
+// Query parameters: grid (ASCII art as read by POST /pathfinder, with S and E),
+// algorithm, delay (milliseconds between steps, default 20) and the
+// diagonal, corners and heuristic options of GET /pathfinder.
+// Errors are sent as fail events since EventSource can't read error responses.
+func StreamPathfinder(w http.ResponseWriter, r *http.Request) {
+	// The middleware may wrap w; the ResponseController reaches the server's
+	// writer through their Unwrap methods
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let proxies buffer the stream
+
+	send := func(event string, data any) {
+		var payload []byte
+		if s, ok := data.(string); ok {
+			payload = []byte(s)
+		} else {
+			payload, _ = json.Marshal(data)
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		rc.Flush()
+	}
+
+	job, err := parseStreamJob(r)
+	if err != nil {
+		send("fail", err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	steps := 0
+	job.opts.OnExpand = func(p pathfinder.Point) {
+		steps++
+		if steps > maxStreamSteps || ctx.Err() != nil {
+			return // client went away or enough steps shown; let the search finish quickly
+		}
+		send("visit", p)
+		select {
+		case <-time.After(job.delay):
+		case <-ctx.Done():
+		}
+	}
+
+	started := time.Now()
+	res, err := job.search(job.grid, job.start, job.end, job.opts)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		send("fail", err.Error())
+		return
+	}
+	send("done", PathfinderResponse{
+		Algorithm:     res.Algorithm,
+		PathLength:    res.Length,
+		ExecutionTime: time.Since(started).Seconds() * 1000, // includes the animation delay
+		Path:          res.Path,
+		Cost:          res.Cost,
+		NodesExpanded: res.NodesExpanded,
+	})
+}
+
+// streamJob is a validated GET /pathfinder/stream request.
+type streamJob struct {
+	grid       [][]int
+	start, end pathfinder.Point
+	search     pathfinder.SearchFunc
+	opts       pathfinder.Options
+	delay      time.Duration
+}
+
+// parseStreamJob parses and validates the query of GET /pathfinder/stream.
+func parseStreamJob(r *http.Request) (streamJob, error) {
+	var job streamJob
+	q := r.URL.Query()
+
+	grid, start, end, err := pathfinder.ParseASCII(q.Get("grid"))
+	if err != nil {
+		return job, err
+	}
+	if len(grid) > maxStreamSide || len(grid[0]) > maxStreamSide {
+		return job, fmt.Errorf("grid is larger than %dx%d", maxStreamSide, maxStreamSide)
+	}
+	if start == nil || end == nil {
+		return job, fmt.Errorf("grid needs a start (S) and an end (E)")
+	}
+	job.grid, job.start, job.end = grid, *start, *end
+
+	name := q.Get("algorithm")
+	search, ok := pathfinder.Algorithms[name]
+	if !ok {
+		return job, fmt.Errorf("unknown algorithm %q", name)
+	}
+	if name == "brute" && len(grid)*len(grid[0]) > maxBruteForceCells {
+		return job, fmt.Errorf("brute force is refused for grids over %d cells", maxBruteForceCells)
+	}
+	job.search = search
+
+	if job.opts, err = pathfinderOptions(r); err != nil {
+		return job, err
+	}
+
+	job.delay = 20 * time.Millisecond
+	if v := q.Get("delay"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 || time.Duration(ms)*time.Millisecond > maxStreamDelay {
+			return job, fmt.Errorf("delay must be 0-%d milliseconds", maxStreamDelay.Milliseconds())
+		}
+		job.delay = time.Duration(ms) * time.Millisecond
+	}
+	return job, nil
+}
@@ -3,6 +3,9 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 )
 
 // RuntimeErrorsHandler provides examples of common Go runtime errors.
@@ -12,7 +15,7 @@ func RuntimeErrorsHandler(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if rec := recover(); rec != nil {
-			http.Error(w, fmt.Sprintf("Recovered from panic: %v", rec), http.StatusInternalServerError)
+			respond.Problem(w, r, problem.New(http.StatusInternalServerError, fmt.Sprintf("Recovered from panic: %v.", rec)))
 		}
 	}()
 
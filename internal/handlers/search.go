@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+	"github.com/shahinzaman102/Go_JumpStart/internal/search"
+)
+
+// searchIndex holds albums, books and wiki pages for GET /search.
+// Handlers that change those resources keep it up to date.
+var searchIndex = search.NewIndex()
+
+// RebuildSearchIndex re-indexes every album (DB), book (in-memory store)
+// and wiki page (data/ directory). Call once on startup.
+func RebuildSearchIndex() error {
+	searchIndex.Reset()
+
+	albums, err := data.AllAlbums()
+	if err != nil {
+		return err
+	}
+	for _, a := range albums {
+		indexAlbum(a)
+	}
+
+	for _, b := range data.GetAllBooks() {
+		indexBook(b)
+	}
+
+	titles, err := wikiStore.List()
+	if err != nil {
+		return err
+	}
+	for _, title := range titles {
+		p, err := loadPage(title)
+		if err != nil {
+			return err
+		}
+		indexWikiPage(p)
+	}
+
+	log.Printf("search index rebuilt: %d documents", searchIndex.Len())
+	return nil
+}
+
+func indexAlbum(a models.Album) {
+	searchIndex.Add(search.Document{
+		Type:  "album",
+		ID:    strconv.FormatInt(a.ID, 10),
+		Title: a.Title,
+		Body:  a.Artist,
+	})
+}
+
+func indexBook(b models.Book) {
+	searchIndex.Add(search.Document{
+		Type:  "book",
+		ID:    strconv.Itoa(b.ID),
+		Title: b.Title,
+		Body:  b.Author,
+	})
+}
+
+func indexWikiPage(p *Page) {
+	searchIndex.Add(search.Document{
+		Type:  "wiki",
+		ID:    p.Title,
+		Title: p.Title,
+		Body:  string(p.Body),
+	})
+}
+
+// Search handles GET /search?q=...&type=album|book|wiki&limit=N
+// and returns BM25-ranked results with highlighted snippets.
+func Search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Query parameter 'q' is required."))
+		return
+	}
+
+	typ := r.URL.Query().Get("type")
+	if typ != "" && typ != "album" && typ != "book" && typ != "wiki" {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "The type parameter must be one of: album, book, wiki."))
+		return
+	}
+
+	limit := 10
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 || n > 50 {
+			respond.Problem(w, r, problem.New(http.StatusBadRequest, "The limit parameter must be between 1 and 50."))
+			return
+		}
+		limit = n
+	}
+
+	results := searchIndex.Search(q, typ, limit)
+
+	respond.WriteJSON(w, http.StatusOK, map[string]any{
+		"query":   q,
+		"count":   len(results),
+		"results": results,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"html/template"
 	"net/http"
 	"path/filepath"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 )
 
 // TestUI serves the test_ui.html template from the filesystem.
@@ -11,7 +13,7 @@ func TestUI(w http.ResponseWriter, r *http.Request) {
 	// Parse template from the templates/ folder on disk
 	tmpl, err := template.ParseFiles(filepath.Join("templates", "test_ui.html"))
 	if err != nil {
-		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
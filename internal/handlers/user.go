@@ -1,14 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/binding"
 	"github.com/shahinzaman102/Go_JumpStart/internal/data"
 	"github.com/shahinzaman102/Go_JumpStart/internal/models"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -29,22 +30,12 @@ func mapUser(u models.User) UserResponse {
 	}
 }
 
-// GetUsers returns a list of all users
+// GetUsers streams the list of all users as they are read
 func GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := data.GetAllUsers()
-	if err != nil {
-		http.Error(w, "Error fetching users", http.StatusInternalServerError)
-		return
-	}
-
-	resp := make([]*UserResponse, len(users))
-	for i, u := range users {
-		userResp := mapUser(u)
-		resp[i] = &userResp
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	s := respond.NewStream(w, r)
+	s.End(data.EachUser(r.Context(), func(u models.User) error {
+		return s.Send(mapUser(u))
+	}))
 }
 
 // GetUserByID returns a single user by ID
@@ -52,60 +43,42 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid user ID."))
 		return
 	}
 
 	user, err := data.GetUserByID(id)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		respond.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(mapUser(*user))
+	respond.Write(w, r, http.StatusOK, mapUser(*user))
 }
 
 // CreateUser adds a new user to the database
 func CreateUser(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username string `json:"username" validate:"trim,required,min=3,max=50"` // trim removes extra spaces
+		Password string `json:"password" validate:"trim,required,min=6"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !binding.Bind(w, r, &input) {
 		return
 	}
 
-	// Trim whitespace
-	input.Username = strings.TrimSpace(input.Username) // removes extra spaces
-	input.Password = strings.TrimSpace(input.Password)
-
-	// Validation
-	if input.Username == "" || input.Password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
-		return
-	}
-	if len(input.Username) < 3 || len(input.Username) > 50 {
-		http.Error(w, "Username must be between 3 and 50 characters", http.StatusBadRequest)
-		return
-	}
-	if len(input.Password) < 6 {
-		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+	id, err := data.CreateUser(input.Username, input.Password)
+	if err != nil {
+		respond.Error(w, r, err) // a 409 if the username is taken
 		return
 	}
 
-	id, err := data.CreateUser(input.Username, input.Password)
+	user, err := data.GetUserByID(int(id))
 	if err != nil {
-		http.Error(w, "Error creating user", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
-	user, _ := data.GetUserByID(int(id))
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]any{
+	respond.Write(w, r, http.StatusCreated, map[string]any{
 		"status":  "success",
 		"message": "User created successfully",
 		"user":    mapUser(*user),
@@ -117,50 +90,35 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid user ID."))
 		return
 	}
 
-	// Trim whitespace
+	// Both fields are optional, but at least one must be given
 	var input struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username string `json:"username" validate:"trim,omitempty,min=3,max=50"`
+		Password string `json:"password" validate:"trim,omitempty,min=6"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !binding.Bind(w, r, &input) {
 		return
 	}
-
-	input.Username = strings.TrimSpace(input.Username)
-	input.Password = strings.TrimSpace(input.Password)
-
 	if input.Username == "" && input.Password == "" {
-		http.Error(w, "No fields to update", http.StatusBadRequest)
-		return
-	}
-	if input.Username != "" && (len(input.Username) < 3 || len(input.Username) > 50) {
-		http.Error(w, "Username must be between 3 and 50 characters", http.StatusBadRequest)
-		return
-	}
-	if input.Password != "" && len(input.Password) < 6 {
-		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusUnprocessableEntity, "The request body has no fields to update."))
 		return
 	}
 
 	if err := data.UpdateUserByID(id, input.Username, input.Password); err != nil {
-		http.Error(w, "Error updating user", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
 	updatedUser, err := data.GetUserByID(id)
 	if err != nil {
-		http.Error(w, "Error fetching updated user", http.StatusInternalServerError)
+		respond.Error(w, r, err) // a 404 if there was no such user to update
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	respond.Write(w, r, http.StatusOK, map[string]any{
 		"status": "success",
 		"user":   mapUser(*updatedUser),
 	})
@@ -171,18 +129,16 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid user ID."))
 		return
 	}
 
 	if err := data.DeleteUserByID(id); err != nil {
-		http.Error(w, "Error deleting user", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	respond.Write(w, r, http.StatusOK, map[string]any{
 		"status": "deleted",
 		"id":     id,
 	})
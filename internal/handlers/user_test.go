@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestUsersMsgPack(t *testing.T) {
+	setupWebSocketAuth(t) // a users table with gopher in it
+
+	r := chi.NewRouter()
+	r.Get("/users/{id}", GetUserByID)
+
+	var viaJSON, viaMsgPack UserResponse
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/users/1", nil), respond.JSON, &viaJSON)
+	fetchAs(t, r, httptest.NewRequest(http.MethodGet, "/users/1", nil), respond.MsgPack, &viaMsgPack)
+	if viaJSON.Username != "gopher" || viaJSON != viaMsgPack {
+		t.Errorf("MessagePack %+v differs from JSON %+v", viaMsgPack, viaJSON)
+	}
+
+	// Errors stay problem details, whatever the client accepts
+	req := httptest.NewRequest(http.MethodGet, "/users/2", nil)
+	req.Header.Set("Accept", respond.MsgPack)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected a 404 problem, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
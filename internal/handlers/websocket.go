@@ -1,47 +1,248 @@
 package handlers
 
 import (
+	"errors"
+	"expvar"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/events"
+	"github.com/shahinzaman102/Go_JumpStart/internal/hub"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/websocket"
 )
 
 // upgrader upgrades HTTP requests to WebSocket connections
-var upgrader = websocket.Upgrader{}
+var upgrader = websocket.Upgrader{CheckOrigin: checkWebSocketOrigin, EnableCompression: true}
+
+// wsHub connects all WebSocket clients so they can join rooms and message each other
+var wsHub = newWebSocketHub(hub.Config{})
+
+// newWebSocketHub creates a hub whose clients can subscribe to the topics webSocketTopic allows.
+func newWebSocketHub(cfg hub.Config) *hub.Hub {
+	h := hub.New(cfg)
+	h.SetTopicResolver(webSocketTopic)
+	return h
+}
+
+// InitWebSocket replaces the hub with one using cfg's limits, relays the data
+// layer's events to the hub's topic subscribers and publishes the hub's stats
+// as the "websocket" expvar (on the pprof server's /debug/vars).
+// Call it once, at startup.
+func InitWebSocket(cfg hub.Config) {
+	wsHub = newWebSocketHub(cfg)
+	upgrader.EnableCompression = wsHub.Config().Compression
+	events.Subscribe("*", func(e events.Event) {
+		if err := wsHub.Publish(e.Topic, e.Type, e.Data); err != nil {
+			log.Printf("websocket: can't publish %s on %s: %v", e.Type, e.Topic, err)
+		}
+	})
+	expvar.Publish("websocket", expvar.Func(func() any { return wsHub.Stats() }))
+}
+
+// webSocketTopic decides which event topics a user may subscribe to: any
+// album's stock ("albums" for all of them, "album:{id}" for one) and their own
+// orders ("orders:me"), but nobody else's.
+func webSocketTopic(user hub.User, topic string) (string, error) {
+	if topic == "orders:me" {
+		return events.UserOrdersTopic(user.ID), nil
+	}
+	if topic == events.AlbumsTopic {
+		return topic, nil
+	}
+	if id, ok := strings.CutPrefix(topic, "album:"); ok {
+		if albumID, err := strconv.ParseInt(id, 10, 64); err == nil && albumID > 0 {
+			return events.AlbumTopic(albumID), nil
+		}
+	}
+	return "", fmt.Errorf("unknown topic %q (try albums, album:{id} or orders:me)", topic)
+}
+
+// wsOrigins are the other origins (besides the app's own) whose pages may open
+// WebSockets; routes.Register shares its CORS list through SetWebSocketOrigins.
+var wsOrigins []string
+
+// wsTokenTTL is how long a token from /ws/token can be used to connect.
+const wsTokenTTL = time.Hour
+
+// SetWebSocketOrigins sets the origins allowed to open WebSockets.
+func SetWebSocketOrigins(origins []string) {
+	wsOrigins = origins
+}
+
+// checkWebSocketOrigin allows connections from the app's own pages, from the
+// allowed origins and from non-browser clients (which send no Origin header and
+// can't carry a victim's cookies, but still have to authenticate).
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range wsOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocket upgrades the connection of a logged-in user and hands it to the hub,
+// which sends JSON messages (see hub.Message) between the connected clients.
+// Browsers authenticate with the session cookie; other clients can send
+// "Authorization: Bearer <token>" with a token from /ws/token.
+func WebSocket(w http.ResponseWriter, r *http.Request) {
+	// Check the origin before looking at the cookie, so other sites can't use a visitor's session
+	if !checkWebSocketOrigin(r) {
+		respond.Problem(w, r, problem.New(http.StatusForbidden, "Origin not allowed."))
+		return
+	}
+	user, ok := webSocketUser(r)
+	if !ok {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
+	}
 
-// Echo is a WebSocket handler that echoes messages back to the client
-func Echo(w http.ResponseWriter, r *http.Request) { // Handler for /echo endpoint.
 	// Upgrade the HTTP connection to a WebSocket connection
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Upgrade error:", err)
 		return
 	}
-	defer conn.Close() // Ensure connection is closed when function exits
+	wsHub.Serve(conn, user) // the hub's goroutines own the connection from here
+}
+
+// webSocketUser resolves the bearer token or session of a request to a user.
+func webSocketUser(r *http.Request) (hub.User, bool) {
+	if store == nil {
+		return hub.User{}, false
+	}
 
-	// Continuously read messages from the client and echo them back
-	for {
-		msgType, msg, err := conn.ReadMessage()
+	var userID int64
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		id, err := parseWebSocketToken(token, time.Now())
 		if err != nil {
-			log.Println("Read error:", err)
-			break
+			return hub.User{}, false
 		}
-		log.Printf("Received: %s", msg)
-
-		// Echo the message back to the client
-		if err := conn.WriteMessage(msgType, msg); err != nil {
-			log.Println("WebSocket write error:", err)
-			break
+		userID = id
+	} else {
+		if !isAuthenticated(r) {
+			return hub.User{}, false
+		}
+		session, _ := store.Get(r, "session")
+		id, ok := session.Values["user_id"].(int64)
+		if !ok {
+			return hub.User{}, false
 		}
+		userID = id
+	}
+
+	user, err := data.GetUserByID(int(userID))
+	if err != nil {
+		return hub.User{}, false
+	}
+	return hub.User{ID: userID, Name: user.Username}, true
+}
+
+// newWebSocketToken signs a token for userID with the session store's keys.
+// It's named differently from the session cookie so it can't be used as one.
+func newWebSocketToken(userID int64, expires time.Time) (string, error) {
+	values := map[any]any{"user_id": userID, "expires": expires.Unix()}
+	return securecookie.EncodeMulti("ws-token", values, store.Codecs...)
+}
+
+// parseWebSocketToken verifies a token and returns its user ID.
+func parseWebSocketToken(token string, now time.Time) (int64, error) {
+	values := map[any]any{}
+	if err := securecookie.DecodeMulti("ws-token", token, &values, store.Codecs...); err != nil {
+		return 0, err
+	}
+	userID, ok1 := values["user_id"].(int64)
+	expires, ok2 := values["expires"].(int64)
+	if !ok1 || !ok2 {
+		return 0, errors.New("malformed token")
+	}
+	if now.Unix() > expires {
+		return 0, errors.New("token expired")
+	}
+	return userID, nil
+}
+
+// WebSocketToken returns a bearer token for /ws to a logged-in user, for
+// clients that can't send the session cookie (e.g. wscat -H "Authorization: Bearer ...").
+func WebSocketToken(w http.ResponseWriter, r *http.Request) {
+	if store == nil || !isAuthenticated(r) {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
 	}
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int64)
+
+	expires := time.Now().Add(wsTokenTTL)
+	token, err := newWebSocketToken(userID, expires)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respond.WriteJSON(w, http.StatusOK, map[string]any{
+		"token":      token,
+		"expires_at": expires.UTC(),
+	})
+}
+
+// WebSocketRooms returns the hub's rooms and their members, and the topics with subscribers, as JSON.
+// It needs the same login as /ws: the client IDs it lists are what direct messages are sent to.
+func WebSocketRooms(w http.ResponseWriter, r *http.Request) {
+	if _, ok := webSocketUser(r); !ok {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
+	}
+	rooms := map[string][]string{}
+	for _, room := range wsHub.Rooms() {
+		rooms[room] = wsHub.Members(room)
+	}
+	respond.WriteJSON(w, http.StatusOK, map[string]any{
+		"clients": wsHub.Clients(),
+		"rooms":   rooms,
+		"topics":  wsHub.Topics(),
+	})
+}
+
+// WebSocketStats returns the hub's connection and message counters as JSON (login required, like /ws).
+func WebSocketStats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := webSocketUser(r); !ok {
+		respond.Problem(w, r, problem.New(http.StatusUnauthorized, "Login required."))
+		return
+	}
+	respond.WriteJSON(w, http.StatusOK, wsHub.Stats())
 }
 
-// WebsocketPage serves the HTML page for the WebSocket frontend
+// WebsocketPage serves the HTML page for the WebSocket frontend (login required, like /ws)
 func WebsocketPage(w http.ResponseWriter, r *http.Request) {
+	if !isAuthenticated(r) {
+		loginRequired(w, r, "WebSocket rooms", "/websockets")
+		return
+	}
+
 	tmpl := template.Must(template.ParseFiles("templates/websockets.html"))
 	if err := tmpl.Execute(w, nil); err != nil {
-		http.Error(w, "Template execution error", http.StatusInternalServerError)
+		respond.Error(w, r, err)
 	}
 }
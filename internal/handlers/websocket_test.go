@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/hub"
+
+	"github.com/gorilla/sessions"
+	"github.com/gorilla/websocket"
+	_ "modernc.org/sqlite"
+)
+
+// setupWebSocketAuth creates a session store and a users table with one user
+// (ID 1, "gopher") in a database it returns.
+func setupWebSocketAuth(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY, username TEXT, password TEXT, created_at DATETIME
+	);
+	INSERT INTO users VALUES (1, "gopher", "x", "2024-01-01 00:00:00")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.InitDBConnection(db)
+	store = sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	t.Cleanup(func() { store = nil })
+	return db
+}
+
+func TestCheckWebSocketOrigin(t *testing.T) {
+	SetWebSocketOrigins([]string{"http://localhost:3000"})
+	defer SetWebSocketOrigins(nil)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},                         // not a browser
+		{"http://example.com", true},       // the app's own pages (Host is example.com)
+		{"http://localhost:3000", true},    // allowed front end
+		{"http://evil.example.net", false}, // anyone else
+		{"http://localhost:3001", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := checkWebSocketOrigin(r); got != tt.want {
+			t.Errorf("origin %q: got %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestWebSocketToken(t *testing.T) {
+	setupWebSocketAuth(t)
+	now := time.Now()
+
+	token, err := newWebSocketToken(1, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := parseWebSocketToken(token, now); err != nil || id != 1 {
+		t.Errorf("expected user 1, got %d, %v", id, err)
+	}
+	if _, err := parseWebSocketToken(token, now.Add(2*time.Minute)); err == nil {
+		t.Errorf("expected an expired token to be rejected")
+	}
+	if _, err := parseWebSocketToken(token[:len(token)-2], now); err == nil {
+		t.Errorf("expected a tampered token to be rejected")
+	}
+	// A token can't be passed off as a session cookie
+	if err := store.Codecs[0].Decode("session", token, &map[any]any{}); err == nil {
+		t.Errorf("expected the token not to decode as a session cookie")
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	setupWebSocketAuth(t)
+	srv := httptest.NewServer(http.HandlerFunc(WebSocket))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	dial := func(header http.Header) (*websocket.Conn, int) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if err != nil {
+			return nil, resp.StatusCode
+		}
+		return conn, resp.StatusCode
+	}
+
+	if _, status := dial(nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", status)
+	}
+	if _, status := dial(http.Header{"Authorization": {"Bearer nonsense"}}); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a bad token, got %d", status)
+	}
+
+	token, _ := newWebSocketToken(1, time.Now().Add(time.Minute))
+	auth := http.Header{"Authorization": {"Bearer " + token}}
+	auth.Set("Origin", "http://evil.example.net")
+	if _, status := dial(auth); status != http.StatusForbidden {
+		t.Errorf("expected 403 for a foreign origin, got %d", status)
+	}
+
+	auth.Del("Origin")
+	conn, status := dial(auth)
+	if conn == nil {
+		t.Fatalf("expected to connect with a token, got %d", status)
+	}
+	defer conn.Close()
+	var welcome hub.Message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&welcome); err != nil || welcome.User == nil || welcome.User.Name != "gopher" {
+		t.Errorf("expected a welcome for gopher, got %+v, %v", welcome, err)
+	}
+}
+
+func TestWebSocketRoomsAuth(t *testing.T) {
+	setupWebSocketAuth(t)
+	token, _ := newWebSocketToken(1, time.Now().Add(time.Minute))
+
+	for _, h := range []http.HandlerFunc{WebSocketRooms, WebSocketStats} {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/ws/rooms", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without credentials, got %d", w.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/ws/rooms", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		h(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200 with a token, got %d: %s", w.Code, w.Body)
+		}
+	}
+}
+
+func TestWebSocketTopic(t *testing.T) {
+	user := hub.User{ID: 7, Name: "gopher"}
+	for topic, want := range map[string]string{
+		"orders:me": "orders:user:7",
+		"albums":    "albums",
+		"album:42":  "album:42",
+		"album:042": "album:42",
+	} {
+		if got, err := webSocketTopic(user, topic); err != nil || got != want {
+			t.Errorf("webSocketTopic(%q) = %q, %v; want %q", topic, got, err, want)
+		}
+	}
+	for _, topic := range []string{"orders:user:8", "orders:user:7", "album:0", "album:x", "lobby"} {
+		if got, err := webSocketTopic(user, topic); err == nil {
+			t.Errorf("webSocketTopic(%q) = %q, want an error", topic, got)
+		}
+	}
+}
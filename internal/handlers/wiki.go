@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/url"
-	"os"
-	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
+	"github.com/shahinzaman102/Go_JumpStart/internal/wiki"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -17,106 +24,458 @@ type Page struct {
 	Body  []byte // Page content as raw bytes
 }
 
-// save writes the Page's body to a file in data/<Title>.txt
-func (p *Page) save() error {
-	if err := os.MkdirAll("data", 0700); err != nil { // ensure directory exists
+// wikiStore holds pages, their revision history and attachments (see InitWikiStore)
+var wikiStore wiki.Store
+
+// wikiLockTTL is how long an advisory edit lock lasts after opening the edit form
+const wikiLockTTL = 10 * time.Minute
+
+// maxWikiForm caps the size of the save and revert forms, page body included
+const maxWikiForm = 1 << 20
+
+// wikiLocks holds advisory edit locks (in memory; they don't survive restarts)
+var wikiLocks = wiki.NewLockTable(wikiLockTTL)
+
+// wikiLinks is the link graph between pages, kept current by Page.save
+var wikiLinks = wiki.NewLinkGraph()
+
+// InitWikiStore sets the store wiki pages are kept in (a wiki.FileStore or
+// wiki.SQLStore) and builds the link graph from it.
+func InitWikiStore(s wiki.Store) error {
+	wikiStore = s
+
+	titles, err := wikiStore.List()
+	if err != nil {
+		return err
+	}
+	wikiLinks = wiki.NewLinkGraph()
+	for _, title := range titles {
+		body, err := wikiStore.Load(title)
+		if err != nil {
+			return err
+		}
+		wikiLinks.Update(title, body)
+	}
+	return nil
+}
+
+// save writes the Page's body through the wiki store, records a revision
+// and updates the link graph
+func (p *Page) save(author, summary, base string) error {
+	_, err := wikiStore.SaveIf(p.Title, base, wiki.Revision{Author: author, Summary: summary, Body: string(p.Body)})
+	if err != nil {
 		return err
 	}
-	return os.WriteFile("data/"+p.Title+".txt", p.Body, 0600) // file accessible only by owner
+	wikiLinks.Update(p.Title, p.Body)
+	return nil
 }
 
-// loadPage reads a page from disk and returns a Page struct
+// wikiAuthor returns the logged-in username for revision records, or "anonymous".
+func wikiAuthor(r *http.Request) string {
+	if store == nil || !isAuthenticated(r) {
+		return "anonymous"
+	}
+	session, _ := store.Get(r, "session")
+	userID, ok := session.Values["user_id"].(int64)
+	if !ok {
+		return "anonymous"
+	}
+	user, err := data.GetUserByID(int(userID))
+	if err != nil {
+		return "anonymous"
+	}
+	return user.Username
+}
+
+// loadPage reads a page from the wiki store and returns a Page struct
 func loadPage(title string) (*Page, error) {
-	body, err := os.ReadFile("data/" + title + ".txt")
+	body, err := wikiStore.Load(title)
 	if err != nil {
 		return nil, err
 	}
 	return &Page{Title: title, Body: body}, nil
 }
 
+// wikiTitle extracts the page title from the wildcard part of the URL and validates it.
+// Namespaced titles arrive as /view/Guides/Setup (or the encoded Guides%2FSetup).
+// On failure it writes a 400 response and returns false.
+func wikiTitle(w http.ResponseWriter, r *http.Request) (string, bool) {
+	title, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil || wiki.ValidateTitle(title) != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid page title."))
+		return "", false
+	}
+	return title, true
+}
+
+// parseWikiForm reads a wiki form of at most maxWikiForm bytes. It writes the
+// error response and returns false if the form is too large or malformed.
+func parseWikiForm(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWikiForm)
+	if err := r.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respond.Problem(w, r, problem.New(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("The form must not exceed %d bytes.", maxErr.Limit)))
+			return false
+		}
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid form."))
+		return false
+	}
+	return true
+}
+
+// wikiURL builds "/<action>/<title>", escaping each namespace segment.
+func wikiURL(action, title string) string {
+	return wiki.PageURL(action, title)
+}
+
 // wikiTemplates is loaded lazily to avoid panics during init/tests
 var wikiTemplates *template.Template
 
-// LoadWikiTemplates parses the edit and view templates
+// LoadWikiTemplates parses the edit, conflict, view, history, diff, index and attachment templates
 func LoadWikiTemplates() error {
 	var err error
-	wikiTemplates, err = template.ParseFiles("templates/edit.html", "templates/view.html")
+	wikiTemplates, err = template.ParseFiles("templates/edit.html", "templates/conflict.html",
+		"templates/view.html", "templates/history.html", "templates/diff.html", "templates/wiki_index.html",
+		"templates/attachments.html")
 	return err
 }
 
-// validLink matches [[PageName]] style links in wiki text
-var validLink = regexp.MustCompile(`\[(.+?)\]`)
-
-// renderTemplate processes links and renders a wiki template
-func renderTemplate(w http.ResponseWriter, tmpl string, p *Page) {
-	bodyStr := string(p.Body)
+// pageExists reports whether a wiki page has been saved (used for red links).
+// It asks the link graph, which has every page, rather than the store, so
+// rendering a page doesn't take a store read per link.
+func pageExists(title string) bool {
+	return wikiLinks.Exists(title)
+}
 
-	// Convert [[PageName]] into <a href="/view/PageName">PageName</a>
-	processed := validLink.ReplaceAllStringFunc(bodyStr, func(s string) string {
-		m := validLink.FindStringSubmatch(s)
-		link := m[1]
-		escaped := url.PathEscape(link) // this encodes "page 1" to "page%201"
-		return fmt.Sprintf(`<a href="/view/%s">%s</a>`, escaped, link)
-	})
+// renderTemplate converts the page's Markdown to sanitised HTML and renders a wiki template
+func renderTemplate(w http.ResponseWriter, r *http.Request, tmpl string, p *Page) {
+	rendered, err := wiki.Render(p.Title, p.Body, pageExists)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
 
-	err := wikiTemplates.ExecuteTemplate(w, tmpl+".html", struct {
-		Title string
-		Body  template.HTML
+	err = wikiTemplates.ExecuteTemplate(w, tmpl+".html", struct {
+		Title     string
+		Body      template.HTML
+		Backlinks []string
 	}{
-		Title: p.Title,
-		Body:  template.HTML(processed), // trusted HTML
+		Title:     p.Title,
+		Body:      template.HTML(rendered), // sanitised by wiki.Render
+		Backlinks: wikiLinks.Backlinks(p.Title),
 	})
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 	}
 }
 
 // ViewWiki handles GET /view/{title}
 // If page exists, it renders it; else redirects to /edit/{title}
 func ViewWiki(w http.ResponseWriter, r *http.Request) {
-	title := chi.URLParam(r, "title")
-	decodedTitle, _ := url.PathUnescape(title)
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
 
-	p, err := loadPage(decodedTitle)
+	p, err := loadPage(title)
+	if err == wiki.ErrPageNotFound {
+		http.Redirect(w, r, wikiURL("edit", title), http.StatusFound)
+		return
+	}
 	if err != nil {
-		http.Redirect(w, r, "/edit/"+decodedTitle, http.StatusFound)
+		respond.Error(w, r, err)
 		return
 	}
-	renderTemplate(w, "view", p)
+	renderTemplate(w, r, "view", p)
 }
 
 // EditWiki handles GET /edit/{title}
-// Loads existing page or prepares an empty one for editing
+// Loads existing page or prepares an empty one for editing. The form carries the
+// hash of the body it started from, and logged-in users take an advisory edit lock.
 func EditWiki(w http.ResponseWriter, r *http.Request) {
-	title := chi.URLParam(r, "title")
-	decodedTitle, _ := url.PathUnescape(title)
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
 
-	p, err := loadPage(decodedTitle)
-	if err != nil {
-		p = &Page{Title: decodedTitle} // new empty page
+	p, err := loadPage(title)
+	if err == wiki.ErrPageNotFound {
+		p = &Page{Title: title} // new empty page
+	} else if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	// Anonymous users all share one name, so they only see locks and never take them
+	var ownLock, otherLock *wiki.Lock
+	if author := wikiAuthor(r); author != "anonymous" {
+		if l, acquired := wikiLocks.Acquire(title, author); acquired {
+			ownLock = &l
+		} else {
+			otherLock = &l
+		}
+	} else if l, locked := wikiLocks.Get(title); locked {
+		otherLock = &l
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/edit.html"))
-	err = tmpl.Execute(w, p)
+	err = wikiTemplates.ExecuteTemplate(w, "edit.html", map[string]any{
+		"Title":     p.Title,
+		"Body":      p.Body,
+		"Base":      wiki.Hash(string(p.Body)),
+		"OwnLock":   ownLock,
+		"OtherLock": otherLock,
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respond.Error(w, r, err)
 	}
 }
 
 // SaveWiki handles POST /save/{title}
-// Saves submitted form content and redirects to /view/{title}
+// Saves submitted form content and redirects to /view/{title}.
+// If the page changed since the form's base revision, nothing is saved and a
+// three-way merge view is shown instead (409 Conflict).
 func SaveWiki(w http.ResponseWriter, r *http.Request) {
-	title := chi.URLParam(r, "title")
-	decodedTitle, _ := url.PathUnescape(title)
+	title, ok := wikiTitle(w, r)
+	if !ok || !parseWikiForm(w, r) {
+		return
+	}
 
 	body := r.FormValue("body")
-	p := &Page{Title: decodedTitle, Body: []byte(body)}
+	summary := r.FormValue("summary")
+	author := wikiAuthor(r)
+
+	// Forms without a base (older clients) keep last-write-wins behaviour
+	base := r.FormValue("base")
+	p := &Page{Title: title, Body: []byte(body)}
+	err := p.save(author, summary, base)
+	if err == wiki.ErrEditConflict {
+		current, err := wikiStore.Load(title)
+		if err != nil && err != wiki.ErrPageNotFound {
+			respond.Error(w, r, err)
+			return
+		}
+		renderConflict(w, r, title, base, body, string(current), summary)
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	wikiLocks.Release(title, author)
+	indexWikiPage(p)
+
+	http.Redirect(w, r, wikiURL("view", title), http.StatusFound)
+}
+
+// renderConflict shows the three-way merge view for an edit based on an outdated
+// revision. The form in it is based on the current body, so submitting it saves normally.
+func renderConflict(w http.ResponseWriter, r *http.Request, title, base, mine, current, summary string) {
+	revs, err := wikiStore.Revisions(title)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	// Find the revision the edit started from; an unknown base (or a page that
+	// didn't exist yet) merges against an empty text
+	var baseBody string
+	for i := len(revs) - 1; i >= 0; i-- {
+		if wiki.Hash(revs[i].Body) == base {
+			baseBody = revs[i].Body
+			break
+		}
+	}
+
+	var latest wiki.Revision
+	if len(revs) > 0 {
+		latest = revs[len(revs)-1]
+	}
+	merged, conflicts := wiki.Merge3(baseBody, mine, current)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusConflict)
+	err = wikiTemplates.ExecuteTemplate(w, "conflict.html", map[string]any{
+		"Title":        title,
+		"Base":         wiki.Hash(current),
+		"Summary":      summary,
+		"Latest":       latest,
+		"Merged":       merged,
+		"Conflicts":    conflicts,
+		"TheirChanges": wiki.Diff(baseBody, current),
+		"YourChanges":  wiki.Diff(baseBody, mine),
+	})
+	if err != nil {
+		respond.Error(w, r, err)
+	}
+}
+
+// WikiIndex handles GET /wiki/index
+// Lists every page with its backlink count, plus orphan and wanted pages
+func WikiIndex(w http.ResponseWriter, r *http.Request) {
+	type pageEntry struct {
+		Title     string
+		Backlinks int
+	}
+	var pages []pageEntry
+	for _, title := range wikiLinks.Pages() {
+		pages = append(pages, pageEntry{Title: title, Backlinks: len(wikiLinks.Backlinks(title))})
+	}
+
+	err := wikiTemplates.ExecuteTemplate(w, "wiki_index.html", map[string]any{
+		"Pages":   pages,
+		"Orphans": wikiLinks.Orphans(),
+		"Wanted":  wikiLinks.Wanted(),
+	})
+	if err != nil {
+		respond.Error(w, r, err)
+	}
+}
+
+// HistoryWiki handles GET /history/{title}
+// Lists every revision of a page, newest first
+func HistoryWiki(w http.ResponseWriter, r *http.Request) {
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
+
+	revs, err := wikiStore.Revisions(title)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	if len(revs) == 0 {
+		respond.NotFound(w, r)
+		return
+	}
+
+	// Newest first
+	newestFirst := make([]wiki.Revision, len(revs))
+	for i, rev := range revs {
+		newestFirst[len(revs)-1-i] = rev
+	}
+
+	// Reverts carry the body they were offered against, like edits
+	current, err := wikiStore.Load(title)
+	if err != nil && err != wiki.ErrPageNotFound {
+		respond.Error(w, r, err)
+		return
+	}
+
+	err = wikiTemplates.ExecuteTemplate(w, "history.html", map[string]any{
+		"Title":     title,
+		"Revisions": newestFirst,
+		"Latest":    len(revs),
+		"Base":      wiki.Hash(string(current)),
+	})
+	if err != nil {
+		respond.Error(w, r, err)
+	}
+}
+
+// DiffWiki handles GET /diff/{title}?from=N&to=M
+// Shows a line-based diff between two revisions (defaults: latest vs. the one before it)
+func DiffWiki(w http.ResponseWriter, r *http.Request) {
+	title, ok := wikiTitle(w, r)
+	if !ok {
+		return
+	}
+
+	revs, err := wikiStore.Revisions(title)
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	if len(revs) == 0 {
+		respond.NotFound(w, r)
+		return
+	}
+
+	to, err := revisionParam(r, "to", len(revs))
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid 'to' revision."))
+		return
+	}
+	from, err := revisionParam(r, "from", max(to-1, 1))
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid 'from' revision."))
+		return
+	}
+	if from < 1 || from > len(revs) || to < 1 || to > len(revs) {
+		respond.Problem(w, r, problem.New(http.StatusNotFound, wiki.ErrRevisionNotFound.Error()))
+		return
+	}
+	fromRev, toRev := revs[from-1], revs[to-1]
+
+	err = wikiTemplates.ExecuteTemplate(w, "diff.html", map[string]any{
+		"Title": title,
+		"From":  fromRev,
+		"To":    toRev,
+		"Lines": wiki.Diff(fromRev.Body, toRev.Body),
+	})
+	if err != nil {
+		respond.Error(w, r, err)
+	}
+}
 
-	if err := p.save(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// RevertWiki handles POST /revert/{title}/{rev}
+// Saves the content of an older revision as a new revision
+func RevertWiki(w http.ResponseWriter, r *http.Request) {
+	// The revision number is the last path segment; everything before it is the title
+	rest := chi.URLParam(r, "*")
+	i := strings.LastIndex(rest, "/")
+	if i < 0 {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid revision number."))
+		return
+	}
+	number, err := strconv.Atoi(rest[i+1:])
+	if err != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid revision number."))
+		return
+	}
+	title, err := url.PathUnescape(rest[:i])
+	if err != nil || wiki.ValidateTitle(title) != nil {
+		respond.Problem(w, r, problem.New(http.StatusBadRequest, "Invalid page title."))
+		return
+	}
+	if !parseWikiForm(w, r) {
 		return
 	}
 
-	http.Redirect(w, r, "/view/"+decodedTitle, http.StatusFound)
+	rev, err := wikiStore.Revision(title, number)
+	if err == wiki.ErrRevisionNotFound {
+		respond.Problem(w, r, problem.New(http.StatusNotFound, err.Error()))
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+
+	p := &Page{Title: title, Body: []byte(rev.Body)}
+	err = p.save(wikiAuthor(r), fmt.Sprintf("Revert to revision %d", number), r.FormValue("base"))
+	if err == wiki.ErrEditConflict {
+		respond.Problem(w, r, problem.New(http.StatusConflict,
+			"The page changed since its history was loaded. Reload the history and try again."))
+		return
+	}
+	if err != nil {
+		respond.Error(w, r, err)
+		return
+	}
+	indexWikiPage(p)
+
+	http.Redirect(w, r, wikiURL("history", title), http.StatusSeeOther)
+}
+
+// revisionParam parses a revision number from the query string, using def if absent.
+func revisionParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
 }
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/wiki"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// errWikiDown stands in for a database or filesystem failure.
+var errWikiDown = errors.New("dial tcp 10.0.0.3:3306: connection refused")
+
+// brokenWikiStore is a wiki store whose reads fail.
+type brokenWikiStore struct {
+	wiki.Store
+}
+
+func (brokenWikiStore) Load(string) ([]byte, error) {
+	return nil, errWikiDown
+}
+
+func (brokenWikiStore) Revision(string, int) (wiki.Revision, error) {
+	return wiki.Revision{}, errWikiDown
+}
+
+// useWikiStore makes s the wiki store for the rest of the test.
+func useWikiStore(t *testing.T, s wiki.Store) {
+	old := wikiStore
+	wikiStore = s
+	t.Cleanup(func() { wikiStore = old })
+}
+
+func TestRevertWikiErrors(t *testing.T) {
+	fs, err := wiki.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	router := chi.NewRouter()
+	router.Post("/revert/*", RevertWiki)
+	revert := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/revert/Missing/1", strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	useWikiStore(t, fs)
+	if w := revert(); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing revision, got %d", w.Code)
+	}
+
+	// Store failures aren't reported as missing revisions, nor shown
+	useWikiStore(t, brokenWikiStore{fs})
+	if w := revert(); w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "3306") {
+		t.Errorf("expected a 500 without the error, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestWikiLoadErrors(t *testing.T) {
+	fs, err := wiki.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	router := chi.NewRouter()
+	router.Get("/view/*", ViewWiki)
+	router.Get("/edit/*", EditWiki)
+	router.Get("/attachments/*", ListAttachments)
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	useWikiStore(t, fs)
+	if w := get("/view/Missing"); w.Code != http.StatusFound || w.Header().Get("Location") != "/edit/Missing" {
+		t.Errorf("expected a missing page to redirect to its edit form, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := get("/attachments/Missing"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing page's attachments, got %d", w.Code)
+	}
+
+	// A store failure isn't mistaken for a missing page
+	useWikiStore(t, brokenWikiStore{fs})
+	for _, target := range []string{"/view/Home", "/edit/Home", "/attachments/Home"} {
+		if w := get(target); w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected 500, got %d", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package hub
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SendQueueSize is how many outgoing messages a client may have waiting.
+// A client whose queue is full is too slow to keep up and is disconnected,
+// so it can't hold up messages to everyone else.
+const SendQueueSize = 64
+
+// User identifies who is behind a connection.
+type User struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Client is one connection to the hub. A user may have several.
+type Client struct {
+	ID   string
+	User User
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte // encoded messages waiting for the write goroutine; closed on unregister
+
+	limiter *rateLimiter // used only by the read goroutine
+
+	rooms  map[string]bool // guarded by hub.mu
+	topics map[string]bool // guarded by hub.mu
+}
+
+// readPump hands every message from the connection to the hub until the
+// connection fails, closes or goes quiet, then unregisters the client.
+// Pongs (answers to writePump's pings) push the read deadline back.
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.Unregister(c)
+		c.conn.Close()
+	}()
+	cfg := c.hub.cfg
+	c.conn.SetReadLimit(cfg.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	})
+
+	for {
+		data, err := c.readMessage()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, websocket.ErrReadLimit):
+				c.hub.stats.oversized.Add(1) // gorilla has already sent close code 1009
+			case errors.As(err, &netErr) && netErr.Timeout():
+				c.hub.stats.timedOut.Add(1)
+			case websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure):
+				log.Printf("hub: read error from %s: %v", c.ID, err)
+			}
+			return
+		}
+		c.receive(data)
+	}
+}
+
+// readMessage reads the next message. SetReadLimit only bounds the compressed
+// frames, so the decompressed message is limited too, or a small compressed
+// message could expand to any size.
+func (c *Client) readMessage() ([]byte, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	max := c.hub.cfg.MaxMessageSize
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err == nil && int64(len(data)) > max {
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseMessageTooBig, ""), time.Now().Add(c.hub.cfg.WriteWait))
+		return nil, websocket.ErrReadLimit
+	}
+	return data, err
+}
+
+// receive passes a message to the hub if it's within the client's rate limit.
+func (c *Client) receive(data []byte) {
+	c.hub.stats.received.Add(1)
+	if !c.limiter.allow(c.hub.now()) {
+		c.hub.stats.rateLimited.Add(1)
+		c.hub.stats.dropped.Add(1)
+		c.hub.reply(c, errorMessage("rate limit exceeded, message dropped"))
+		return
+	}
+	c.hub.handle(c, data)
+}
+
+// writePump is the only goroutine writing to the connection. It sends queued
+// messages and periodic pings until the queue is closed or a write fails or
+// takes longer than WriteWait, then closes the connection.
+func (c *Client) writePump() {
+	cfg := c.hub.cfg
+	ticker := time.NewTicker(cfg.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.writeFailed(err)
+				return
+			}
+			c.hub.stats.sent.Add(1)
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.writeFailed(err)
+				return
+			}
+		}
+	}
+}
+
+// writeFailed records why a write failed.
+func (c *Client) writeFailed(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		c.hub.stats.timedOut.Add(1)
+	}
+}
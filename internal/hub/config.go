@@ -0,0 +1,87 @@
+package hub
+
+import "time"
+
+// Config holds the per-connection limits of a hub. Zero fields take their
+// value from DefaultConfig.
+type Config struct {
+	MaxMessageSize int64         // largest message accepted from a client, in bytes; larger ones close the connection
+	PongWait       time.Duration // how long a client may go without answering a ping before it's considered dead
+	PingPeriod     time.Duration // how often clients are pinged; must be less than PongWait
+	WriteWait      time.Duration // time allowed to write one message before the client is considered dead
+	RateLimit      float64       // messages per second a client may send on average; negative means unlimited
+	RateBurst      int           // messages a client may send in a burst on top of the average
+	Compression    bool          // compress messages (permessage-deflate) for clients that support it
+}
+
+// DefaultConfig returns the limits used for zero Config fields.
+func DefaultConfig() Config {
+	return Config{
+		MaxMessageSize: 32 << 10,
+		PongWait:       60 * time.Second,
+		PingPeriod:     54 * time.Second,
+		WriteWait:      10 * time.Second,
+		RateLimit:      10,
+		RateBurst:      20,
+		Compression:    true,
+	}
+}
+
+// withDefaults fills in zero fields. Compression can't be told apart from
+// "unset", so it's only enabled by default for an entirely zero Config.
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c == (Config{}) {
+		return d
+	}
+	if c.MaxMessageSize == 0 {
+		c.MaxMessageSize = d.MaxMessageSize
+	}
+	if c.PongWait == 0 {
+		c.PongWait = d.PongWait
+	}
+	if c.PingPeriod == 0 {
+		c.PingPeriod = c.PongWait * 9 / 10
+	}
+	if c.WriteWait == 0 {
+		c.WriteWait = d.WriteWait
+	}
+	if c.RateLimit == 0 {
+		c.RateLimit = d.RateLimit
+	}
+	if c.RateBurst == 0 {
+		c.RateBurst = d.RateBurst
+	}
+	return c
+}
+
+// rateLimiter is a token bucket: it holds up to burst tokens, refilled at rate
+// per second, and each message takes one. Only the client's read goroutine uses it.
+type rateLimiter struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate < 0 {
+		return nil // unlimited
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow reports whether a message received at now is within the limit.
+func (l *rateLimiter) allow(now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,85 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// History stores the messages broadcast to rooms, so clients joining a room
+// can be shown what was said before they arrived.
+type History interface {
+	// Save stores a room broadcast and returns its ID.
+	Save(msg Message) (int64, error)
+	// Recent returns up to n of a room's latest messages, oldest first.
+	Recent(room string, n int) ([]Message, error)
+}
+
+// saveQueueSize is how many room broadcasts may wait to be saved. When the
+// queue is full, broadcasts are delivered without being saved.
+const saveQueueSize = 256
+
+// SetHistory makes the hub save every broadcast to a room in history and
+// send clients that join a room its last replay messages. Set it before
+// serving clients.
+func (h *Hub) SetHistory(history History, replay int) {
+	h.history, h.replay = history, replay
+	h.saves = make(chan Message, saveQueueSize)
+	go h.saveLoop()
+}
+
+// saveAndBroadcast delivers a room broadcast once it has been saved and has
+// its ID. Saving is left to saveLoop, so a slow History doesn't hold up the
+// sender's read goroutine.
+func (h *Hub) saveAndBroadcast(msg Message) {
+	if h.history == nil || msg.Room == "" {
+		h.Broadcast(msg.Room, msg)
+		return
+	}
+	select {
+	case h.saves <- msg:
+	default:
+		log.Printf("hub: too many messages waiting to be saved, %s's isn't", msg.Room)
+		h.Broadcast(msg.Room, msg)
+	}
+}
+
+// saveLoop saves the queued room broadcasts in order, delivering each after it's saved.
+func (h *Hub) saveLoop() {
+	for msg := range h.saves {
+		h.save(&msg)
+		h.Broadcast(msg.Room, msg)
+	}
+}
+
+// save stores a room broadcast from a client and sets its ID. Messages that
+// can't be saved are still delivered, just not replayed later.
+func (h *Hub) save(msg *Message) {
+	if h.history == nil || msg.Room == "" {
+		return
+	}
+	id, err := h.history.Save(*msg)
+	if err != nil {
+		log.Printf("hub: can't save message to %s: %v", msg.Room, err)
+		return
+	}
+	msg.ID = id
+}
+
+// replayHistory sends c a History message with room's latest messages.
+// Messages broadcast while they're loaded may arrive twice; clients can tell
+// by their IDs.
+func (h *Hub) replayHistory(c *Client, room string) {
+	if h.history == nil || h.replay <= 0 {
+		return
+	}
+	msgs, err := h.history.Recent(room, h.replay)
+	if err != nil {
+		log.Printf("hub: can't load the history of %s: %v", room, err)
+		return
+	}
+	if msgs == nil {
+		msgs = []Message{}
+	}
+	data, _ := json.Marshal(msgs)
+	h.reply(c, Message{Type: TypeHistory, Room: room, Data: data})
+}
@@ -0,0 +1,398 @@
+// Package hub connects WebSocket clients to each other: clients join named
+// rooms and send broadcasts to a room or direct messages to another client,
+// all wrapped in a JSON Message envelope. Clients can also subscribe to
+// topics, on which the server publishes events (see Publish).
+//
+// Each client has its own write goroutine fed by a bounded queue. The hub
+// never blocks on a client: one whose queue is full is disconnected.
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrNoSuchClient is returned when a direct message's recipient isn't connected.
+var ErrNoSuchClient = errors.New("no such client")
+
+// Hub tracks connected clients and the rooms they're in.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[string]*Client
+	rooms   map[string]map[*Client]bool
+	topics  map[string]map[*Client]bool
+	resolve TopicResolver
+	history History
+	replay  int
+	saves   chan Message // room broadcasts waiting to be saved (see SetHistory)
+	nextID  atomic.Uint64
+	cfg     Config
+	stats   counters
+	now     func() time.Time // replaced in tests
+}
+
+// New creates an empty hub with the given limits.
+func New(cfg Config) *Hub {
+	return &Hub{
+		clients: map[string]*Client{},
+		rooms:   map[string]map[*Client]bool{},
+		topics:  map[string]map[*Client]bool{},
+		cfg:     cfg.withDefaults(),
+		now:     time.Now,
+	}
+}
+
+// Config returns the hub's limits (with defaults filled in).
+func (h *Hub) Config() Config {
+	return h.cfg
+}
+
+// Serve registers conn as a new client of user, sends it a Welcome message with
+// its ID and starts its read and write goroutines. It returns without waiting for them.
+func (h *Hub) Serve(conn *websocket.Conn, user User) *Client {
+	conn.EnableWriteCompression(h.cfg.Compression) // only takes effect if negotiated during the upgrade
+	c := h.Register(conn, user)
+	go c.writePump()
+	go c.readPump()
+	return c
+}
+
+// Register adds a client for conn (which may be nil in tests) and queues its
+// Welcome message. The caller starts the client's pumps.
+func (h *Hub) Register(conn *websocket.Conn, user User) *Client {
+	c := &Client{
+		ID:     fmt.Sprintf("c%d", h.nextID.Add(1)),
+		User:   user,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, SendQueueSize),
+		rooms:  map[string]bool{},
+		topics: map[string]bool{},
+
+		limiter: newRateLimiter(h.cfg.RateLimit, h.cfg.RateBurst),
+	}
+	h.stats.total.Add(1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c.ID] = c
+	h.deliver(c, h.encode(Message{Type: TypeWelcome, To: c.ID, User: &c.User}))
+	return c
+}
+
+// Unregister removes a client from the hub and all its rooms and closes its
+// queue, which makes its write goroutine close the connection. It's safe to
+// call more than once.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(c)
+}
+
+// Join adds c to room and tells the room's members (c included).
+func (h *Hub) Join(c *Client, room string) error {
+	if !ValidRoom(room) {
+		return fmt.Errorf("invalid room name %q", room)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c.ID] != c || c.rooms[room] {
+		return nil // disconnected, or already a member
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*Client]bool{}
+	}
+	h.rooms[room][c] = true
+	c.rooms[room] = true
+	h.broadcast(room, Message{Type: TypeJoin, Room: room, From: c.ID, User: &c.User})
+	return nil
+}
+
+// Leave removes c from room and tells the remaining members.
+func (h *Hub) Leave(c *Client, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !c.rooms[room] {
+		return
+	}
+	h.leave(c, room)
+	h.broadcast(room, Message{Type: TypeLeave, Room: room, From: c.ID, User: &c.User})
+}
+
+// Broadcast sends msg to every member of room, or every client if room is "".
+func (h *Hub) Broadcast(room string, msg Message) {
+	msg.Room = room
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcast(room, msg)
+}
+
+// Direct sends msg to the client with the given ID.
+func (h *Hub) Direct(to string, msg Message) error {
+	msg.To = to
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.clients[to]
+	if !ok {
+		return ErrNoSuchClient
+	}
+	h.deliver(c, h.encode(msg))
+	return nil
+}
+
+// TopicResolver maps the topic a user asked for to the one to subscribe them
+// to, e.g. "orders:me" to that user's own orders, or refuses with an error.
+type TopicResolver func(user User, topic string) (string, error)
+
+// SetTopicResolver makes Subscribe check topics with resolve. Without one, any
+// valid name can be subscribed to as is. Set it before serving clients.
+func (h *Hub) SetTopicResolver(resolve TopicResolver) {
+	h.resolve = resolve
+}
+
+// Subscribe adds c to the subscribers of topic (as resolved by the hub's
+// TopicResolver) and returns the topic subscribed to.
+func (h *Hub) Subscribe(c *Client, topic string) (string, error) {
+	topic, err := h.resolveTopic(c, topic)
+	if err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[c.ID] != c {
+		return topic, nil // disconnected
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[*Client]bool{}
+	}
+	h.topics[topic][c] = true
+	c.topics[topic] = true
+	return topic, nil
+}
+
+// Unsubscribe removes c from the subscribers of topic and returns the topic unsubscribed from.
+func (h *Hub) Unsubscribe(c *Client, topic string) (string, error) {
+	topic, err := h.resolveTopic(c, topic)
+	if err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(c, topic)
+	return topic, nil
+}
+
+// Publish sends an Event message with data encoded as JSON to topic's subscribers.
+func (h *Hub) Publish(topic, event string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.topics[topic]) == 0 {
+		return nil
+	}
+	encoded := h.encode(Message{Type: TypeEvent, Room: topic, Event: event, Data: raw})
+	for c := range h.topics[topic] {
+		h.deliver(c, encoded)
+	}
+	return nil
+}
+
+// Topics returns the names of the topics with at least one subscriber, sorted.
+func (h *Hub) Topics() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.topics))
+	for name := range h.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rooms returns the names of the rooms with at least one member, sorted.
+func (h *Hub) Rooms() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.rooms))
+	for name := range h.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Members returns the IDs of the clients in room, sorted.
+func (h *Hub) Members(room string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		ids = append(ids, c.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// handle acts on a message received from c.
+func (h *Hub) handle(c *Client, data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		h.reply(c, errorMessage("invalid message: "+err.Error()))
+		return
+	}
+	msg.From, msg.User = c.ID, &c.User // clients can't speak for each other
+	msg.ID, msg.Time = 0, h.now().UTC()
+
+	switch msg.Type {
+	case TypeJoin:
+		if err := h.Join(c, msg.Room); err != nil {
+			h.reply(c, errorMessage(err.Error()))
+			return
+		}
+		h.replayHistory(c, msg.Room)
+	case TypeLeave:
+		h.Leave(c, msg.Room)
+	case TypeBroadcast:
+		if msg.Room != "" && !h.isMember(c, msg.Room) {
+			h.reply(c, errorMessage("join room "+msg.Room+" before sending to it"))
+			return
+		}
+		h.saveAndBroadcast(msg)
+	case TypeSubscribe, TypeUnsubscribe:
+		subscribe := h.Subscribe
+		if msg.Type == TypeUnsubscribe {
+			subscribe = h.Unsubscribe
+		}
+		topic, err := subscribe(c, msg.Room)
+		if err != nil {
+			h.reply(c, errorMessage(err.Error()))
+			return
+		}
+		h.reply(c, Message{Type: msg.Type, Room: topic})
+	case TypeDirect:
+		if err := h.Direct(msg.To, msg); err != nil {
+			h.reply(c, errorMessage(fmt.Sprintf("%s: %s", err, msg.To)))
+			return
+		}
+		if msg.To != c.ID {
+			h.Direct(c.ID, msg) // echo so the sender's view shows the conversation
+		}
+	default:
+		h.reply(c, errorMessage(fmt.Sprintf("unknown message type %q", msg.Type)))
+	}
+}
+
+// reply sends a message to c only.
+func (h *Hub) reply(c *Client, msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deliver(c, h.encode(msg))
+}
+
+// isMember reports whether c is in room.
+func (h *Hub) isMember(c *Client, room string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return c.rooms[room]
+}
+
+// broadcast encodes msg once and queues it for every recipient. h.mu must be held.
+func (h *Hub) broadcast(room string, msg Message) {
+	data := h.encode(msg)
+	if room == "" {
+		for _, c := range h.clients {
+			h.deliver(c, data)
+		}
+		return
+	}
+	for c := range h.rooms[room] {
+		h.deliver(c, data)
+	}
+}
+
+// encode stamps (unless it's been stamped already) and marshals a message.
+func (h *Hub) encode(msg Message) []byte {
+	if msg.Time.IsZero() {
+		msg.Time = h.now().UTC()
+	}
+	data, _ := json.Marshal(msg)
+	return data
+}
+
+// deliver queues data for c without blocking, dropping c if its queue is full.
+// h.mu must be held.
+func (h *Hub) deliver(c *Client, data []byte) {
+	if h.clients[c.ID] != c {
+		return // already dropped
+	}
+	select {
+	case c.send <- data:
+	default:
+		h.stats.dropped.Add(1) // data is lost; what's already queued is still sent
+		h.stats.slow.Add(1)
+		h.drop(c)
+	}
+}
+
+// drop removes c from the hub and its rooms and closes its queue. h.mu must be held.
+func (h *Hub) drop(c *Client) {
+	if h.clients[c.ID] != c {
+		return
+	}
+	delete(h.clients, c.ID)
+	for room := range c.rooms {
+		h.leave(c, room)
+		h.broadcast(room, Message{Type: TypeLeave, Room: room, From: c.ID, User: &c.User})
+	}
+	for topic := range c.topics {
+		h.unsubscribe(c, topic)
+	}
+	close(c.send)
+}
+
+// leave removes c from room, deleting the room when it's empty. h.mu must be held.
+func (h *Hub) leave(c *Client, room string) {
+	delete(c.rooms, room)
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// resolveTopic validates topic and passes it through the hub's TopicResolver.
+func (h *Hub) resolveTopic(c *Client, topic string) (string, error) {
+	if !ValidRoom(topic) {
+		return "", fmt.Errorf("invalid topic name %q", topic)
+	}
+	if h.resolve == nil {
+		return topic, nil
+	}
+	return h.resolve(c.User, topic)
+}
+
+// unsubscribe removes c from topic, deleting the topic when it has no
+// subscribers left. h.mu must be held.
+func (h *Hub) unsubscribe(c *Client, topic string) {
+	delete(c.topics, topic)
+	delete(h.topics[topic], c)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
@@ -0,0 +1,435 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// next returns the next message queued for c, failing if there is none.
+func next(t *testing.T, c *Client) Message {
+	t.Helper()
+	select {
+	case data, ok := <-c.send:
+		if !ok {
+			t.Fatalf("%s: queue closed", c.ID)
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("%s: invalid message %s", c.ID, data)
+		}
+		return msg
+	default:
+		t.Fatalf("%s: no message queued", c.ID)
+		return Message{}
+	}
+}
+
+// empty fails if anything is queued for c.
+func empty(t *testing.T, c *Client) {
+	t.Helper()
+	if len(c.send) != 0 {
+		t.Errorf("%s: unexpected message %s", c.ID, <-c.send)
+	}
+}
+
+// register adds a client without a connection and discards its welcome.
+func register(t *testing.T, h *Hub, name string) *Client {
+	c := h.Register(nil, User{ID: int64(len(name)), Name: name})
+	if msg := next(t, c); msg.Type != TypeWelcome || msg.To != c.ID {
+		t.Fatalf("expected a welcome for %s, got %+v", c.ID, msg)
+	}
+	return c
+}
+
+func TestRoomsAndMessages(t *testing.T) {
+	h := New(Config{})
+	alice, bob, carol := register(t, h, "alice"), register(t, h, "bob"), register(t, h, "carol")
+
+	h.handle(alice, []byte(`{"type":"join","room":"lobby"}`))
+	h.handle(bob, []byte(`{"type":"join","room":"lobby"}`))
+	next(t, alice) // own join
+	if msg := next(t, alice); msg.Type != TypeJoin || msg.From != bob.ID {
+		t.Errorf("expected alice to see bob join, got %+v", msg)
+	}
+	next(t, bob)
+
+	// Broadcasts reach the room only, and From can't be forged
+	h.handle(bob, []byte(`{"type":"broadcast","room":"lobby","from":"someone","user":{"name":"alice"},"data":{"text":"hi"}}`))
+	for _, c := range []*Client{alice, bob} {
+		if msg := next(t, c); msg.From != bob.ID || msg.User.Name != "bob" || string(msg.Data) != `{"text":"hi"}` || msg.Time.IsZero() {
+			t.Errorf("%s: unexpected broadcast %+v", c.ID, msg)
+		}
+	}
+	empty(t, carol)
+
+	// Non-members can't broadcast to a room
+	h.handle(carol, []byte(`{"type":"broadcast","room":"lobby","data":1}`))
+	if msg := next(t, carol); msg.Type != TypeError {
+		t.Errorf("expected an error, got %+v", msg)
+	}
+	empty(t, alice)
+
+	// Direct messages go to the recipient, with a copy for the sender
+	h.handle(carol, []byte(`{"type":"direct","to":"`+alice.ID+`","data":"psst"}`))
+	if msg := next(t, alice); msg.Type != TypeDirect || msg.From != carol.ID {
+		t.Errorf("unexpected direct message %+v", msg)
+	}
+	next(t, carol)
+	empty(t, bob)
+	h.handle(carol, []byte(`{"type":"direct","to":"nobody","data":1}`))
+	if msg := next(t, carol); msg.Type != TypeError {
+		t.Errorf("expected an error, got %+v", msg)
+	}
+
+	h.handle(alice, []byte(`{"type":"leave","room":"lobby"}`))
+	if msg := next(t, bob); msg.Type != TypeLeave || msg.From != alice.ID {
+		t.Errorf("expected bob to see alice leave, got %+v", msg)
+	}
+	if members := h.Members("lobby"); len(members) != 1 || members[0] != bob.ID {
+		t.Errorf("unexpected members %v", members)
+	}
+
+	for _, bad := range []string{`not json`, `{"type":"shout"}`, `{"type":"join","room":"no spaces"}`} {
+		h.handle(alice, []byte(bad))
+		if msg := next(t, alice); msg.Type != TypeError {
+			t.Errorf("%s: expected an error, got %+v", bad, msg)
+		}
+	}
+}
+
+func TestTopics(t *testing.T) {
+	h := New(Config{})
+	h.SetTopicResolver(func(u User, topic string) (string, error) {
+		if topic == "mine" {
+			return fmt.Sprintf("user:%d", u.ID), nil
+		}
+		if strings.HasPrefix(topic, "user:") {
+			return "", errors.New("forbidden")
+		}
+		return topic, nil
+	})
+	alice, bob := register(t, h, "alice"), register(t, h, "bob")
+
+	// Subscribing is acknowledged with the resolved topic, and tells no one else
+	h.handle(alice, []byte(`{"type":"subscribe","room":"mine"}`))
+	if msg := next(t, alice); msg.Type != TypeSubscribe || msg.Room != "user:5" {
+		t.Errorf("expected a subscription to user:5, got %+v", msg)
+	}
+	h.handle(bob, []byte(`{"type":"subscribe","room":"user:5"}`))
+	if msg := next(t, bob); msg.Type != TypeError {
+		t.Errorf("expected bob's subscription to be refused, got %+v", msg)
+	}
+	h.handle(bob, []byte(`{"type":"subscribe","room":"album:1"}`))
+	next(t, bob)
+	empty(t, alice)
+
+	// Events reach the topic's subscribers only
+	h.Publish("user:5", "order.created", map[string]int{"id": 1})
+	if msg := next(t, alice); msg.Type != TypeEvent || msg.Room != "user:5" || msg.Event != "order.created" || string(msg.Data) != `{"id":1}` {
+		t.Errorf("unexpected event %+v", msg)
+	}
+	empty(t, bob)
+
+	// Topics aren't rooms: clients can't publish to them
+	h.handle(bob, []byte(`{"type":"broadcast","room":"album:1","data":1}`))
+	if msg := next(t, bob); msg.Type != TypeError {
+		t.Errorf("expected an error, got %+v", msg)
+	}
+
+	h.handle(bob, []byte(`{"type":"unsubscribe","room":"album:1"}`))
+	next(t, bob)
+	h.Publish("album:1", "stock.changed", 0)
+	empty(t, bob)
+
+	h.Unregister(alice)
+	if topics := h.Topics(); len(topics) != 0 {
+		t.Errorf("topics left after everyone unsubscribed: %v", topics)
+	}
+}
+
+// memoryHistory is a History kept in a slice.
+type memoryHistory struct{ msgs []Message }
+
+func (m *memoryHistory) Save(msg Message) (int64, error) {
+	msg.ID = int64(len(m.msgs) + 1)
+	m.msgs = append(m.msgs, msg)
+	return msg.ID, nil
+}
+
+func (m *memoryHistory) Recent(room string, n int) ([]Message, error) {
+	var recent []Message
+	for _, msg := range m.msgs {
+		if msg.Room == room {
+			recent = append(recent, msg)
+		}
+	}
+	return recent[max(len(recent)-n, 0):], nil
+}
+
+func TestHistory(t *testing.T) {
+	h := New(Config{})
+	history := &memoryHistory{}
+	h.SetHistory(history, 2)
+	alice, bob := register(t, h, "alice"), register(t, h, "bob")
+
+	h.handle(alice, []byte(`{"type":"join","room":"lobby"}`))
+	next(t, alice)
+	if msg := next(t, alice); msg.Type != TypeHistory || string(msg.Data) != "[]" {
+		t.Errorf("expected an empty history, got %+v", msg)
+	}
+	for _, text := range []string{`"one"`, `"two"`, `"three"`} {
+		h.handle(alice, []byte(`{"type":"broadcast","room":"lobby","id":99,"data":`+text+`}`))
+		if msg := await(t, alice); msg.ID != int64(len(history.msgs)) {
+			t.Errorf("broadcast has ID %d, want the saved one", msg.ID)
+		}
+	}
+	h.handle(alice, []byte(`{"type":"broadcast","data":"not saved"}`)) // to everyone, not a room
+	next(t, alice)
+	next(t, bob)
+	if len(history.msgs) != 3 {
+		t.Errorf("saved %d messages, want 3", len(history.msgs))
+	}
+
+	// Joining replays the last two messages, with their sender and original time
+	h.handle(bob, []byte(`{"type":"join","room":"lobby"}`))
+	next(t, bob)
+	msg := next(t, bob)
+	var replayed []Message
+	if err := json.Unmarshal(msg.Data, &replayed); err != nil || msg.Type != TypeHistory || msg.Room != "lobby" {
+		t.Fatalf("expected lobby's history, got %+v", msg)
+	}
+	if len(replayed) != 2 || string(replayed[0].Data) != `"two"` || replayed[1].From != alice.ID ||
+		!replayed[1].Time.Equal(history.msgs[2].Time) {
+		t.Errorf("unexpected history %+v", replayed)
+	}
+}
+
+// slowHistory is a History whose saves wait until release is closed.
+type slowHistory struct {
+	memoryHistory
+	release chan struct{}
+}
+
+func (s *slowHistory) Save(msg Message) (int64, error) {
+	<-s.release
+	return s.memoryHistory.Save(msg)
+}
+
+func TestSlowHistory(t *testing.T) {
+	h := New(Config{})
+	history := &slowHistory{release: make(chan struct{})}
+	h.SetHistory(history, 0)
+	alice, bob := register(t, h, "alice"), register(t, h, "bob")
+	h.handle(alice, []byte(`{"type":"join","room":"lobby"}`))
+	next(t, alice)
+
+	// While the broadcast is being saved, alice's next message still goes through
+	h.handle(alice, []byte(`{"type":"broadcast","room":"lobby","data":"saved"}`))
+	h.handle(alice, []byte(`{"type":"direct","to":"`+bob.ID+`","data":"hi"}`))
+	if msg := next(t, bob); msg.Type != TypeDirect {
+		t.Fatalf("expected the direct message while the broadcast is saved, got %+v", msg)
+	}
+	next(t, alice) // the direct message's echo
+	empty(t, alice)
+
+	close(history.release)
+	if msg := await(t, alice); msg.Type != TypeBroadcast || msg.ID != 1 {
+		t.Errorf("expected the saved broadcast, got %+v", msg)
+	}
+}
+
+func TestSlowClientIsDropped(t *testing.T) {
+	h := New(Config{})
+	fast, slow := register(t, h, "fast"), register(t, h, "slow")
+	h.Join(fast, "room")
+	h.Join(slow, "room")
+	next(t, fast)
+	next(t, fast)
+
+	// Nobody reads slow's queue, so it fills up and slow is dropped
+	var notices []Message
+	for i := 0; i < SendQueueSize; i++ {
+		h.Broadcast("room", Message{Type: TypeBroadcast})
+		for len(fast.send) > 0 {
+			if msg := next(t, fast); msg.Type == TypeLeave {
+				notices = append(notices, msg)
+			}
+		}
+	}
+	if h.Clients() != 1 || len(h.Members("room")) != 1 {
+		t.Fatalf("expected the slow client to be dropped, have %d clients", h.Clients())
+	}
+	if len(notices) != 1 || notices[0].From != slow.ID {
+		t.Errorf("expected one leave notice for the slow client, got %+v", notices)
+	}
+	if s := h.Stats(); s.DroppedMessages != 1 || s.SlowClients != 1 {
+		t.Errorf("expected the overflowing message to be dropped, got %+v", s)
+	}
+
+	for range slow.send {
+		// drain until closed
+	}
+	h.Unregister(slow) // dropping twice is harmless
+}
+
+func TestServe(t *testing.T) {
+	h := New(Config{})
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		h.Serve(conn, User{Name: "test"})
+	}))
+	defer srv.Close()
+
+	dial := func() (*websocket.Conn, string) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var welcome Message
+		conn.ReadJSON(&welcome)
+		return conn, welcome.To
+	}
+	a, aID := dial()
+	b, _ := dial()
+	defer a.Close()
+
+	b.WriteJSON(Message{Type: TypeDirect, To: aID, Data: json.RawMessage(`"hello"`)})
+	var msg Message
+	if err := a.ReadJSON(&msg); err != nil || string(msg.Data) != `"hello"` {
+		t.Fatalf("expected the direct message, got %+v, %v", msg, err)
+	}
+
+	b.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Clients() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := h.Clients(); n != 1 {
+		t.Errorf("expected 1 client after closing one, have %d", n)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := New(Config{RateLimit: 1, RateBurst: 2})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	c := register(t, h, "chatty")
+	msg := []byte(`{"type":"broadcast","data":1}`)
+
+	// The burst goes through, the next message is refused until a token is refilled
+	c.receive(msg)
+	c.receive(msg)
+	next(t, c)
+	next(t, c)
+	c.receive(msg)
+	if got := next(t, c); got.Type != TypeError {
+		t.Errorf("expected a rate limit error, got %+v", got)
+	}
+	now = now.Add(time.Second)
+	c.receive(msg)
+	if got := next(t, c); got.Type != TypeBroadcast {
+		t.Errorf("expected the message to pass after a second, got %+v", got)
+	}
+
+	if s := h.Stats(); s.MessagesReceived != 4 || s.RateLimited != 1 || s.DroppedMessages != 1 || s.SlowClients != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+// serveTest starts a server for h and returns a function dialing it.
+func serveTest(t *testing.T, h *Hub) func() *websocket.Conn {
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		h.Serve(conn, User{Name: "test"})
+	}))
+	t.Cleanup(srv.Close)
+
+	return func() *websocket.Conn {
+		dialer := websocket.Dialer{EnableCompression: true}
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var welcome Message
+		if err := conn.ReadJSON(&welcome); err != nil || welcome.Type != TypeWelcome {
+			t.Fatalf("expected a welcome, got %+v, %v", welcome, err)
+		}
+		return conn
+	}
+}
+
+// await waits up to five seconds for c's next message.
+func await(t *testing.T, c *Client) Message {
+	t.Helper()
+	waitFor(t, c.ID+"'s next message", func() bool { return len(c.send) > 0 })
+	return next(t, c)
+}
+
+// waitFor polls cond for up to five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMessageSizeLimit(t *testing.T) {
+	h := New(Config{MaxMessageSize: 64})
+	conn := serveTest(t, h)()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"broadcast","data":"`+strings.Repeat("x", 100)+`"}`))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("expected close code 1009, got %v", err)
+	}
+	waitFor(t, "the client to be dropped", func() bool { return h.Clients() == 0 })
+	if s := h.Stats(); s.OversizedClosed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestDeadPeerTimesOut(t *testing.T) {
+	h := New(Config{PongWait: 100 * time.Millisecond, PingPeriod: 50 * time.Millisecond})
+	dial := serveTest(t, h)
+	alive := dial()
+	dial() // a dead peer: it never reads again, so it never answers pings
+
+	// Reading makes gorilla answer pings
+	go func() {
+		alive.SetReadDeadline(time.Time{})
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitFor(t, "the dead peer to time out", func() bool { return h.Stats().TimedOut == 1 })
+	time.Sleep(200 * time.Millisecond)
+	if s := h.Stats(); s.OpenConnections != 1 || s.TotalConnections != 2 || s.TimedOut != 1 {
+		t.Errorf("expected only the dead peer to be dropped, got %+v", s)
+	}
+}
@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+// Message types. Clients send Join, Leave, Broadcast and Direct; the server
+// relays those (with From and Time set) and sends Welcome and Error itself.
+// Clients also Subscribe to and Unsubscribe from topics, which the server
+// publishes Events on; unlike rooms, topics are read-only to clients.
+const (
+	TypeJoin      = "join"      // join Room; relayed to the room's members
+	TypeLeave     = "leave"     // leave Room; relayed to the room's remaining members
+	TypeBroadcast = "broadcast" // send Data to every member of Room (every client if Room is "")
+	TypeDirect    = "direct"    // send Data to the client whose ID is To
+	TypeWelcome   = "welcome"   // first message on a connection; To is the client's ID and User who it's logged in as
+	TypeError     = "error"     // Data is a JSON string describing what went wrong
+
+	TypeSubscribe   = "subscribe"   // subscribe to topic Room; echoed back with the topic actually subscribed to
+	TypeUnsubscribe = "unsubscribe" // stop receiving topic Room's events; echoed back like Subscribe
+	TypeEvent       = "event"       // something happened on topic Room: Event says what, Data has the details
+	TypeHistory     = "history"     // sent after a join: Data is an array of Room's latest broadcasts, oldest first
+)
+
+// Message is the JSON envelope of everything sent over a hub connection.
+type Message struct {
+	ID    int64           `json:"id,omitempty"` // set on room broadcasts the hub's History has saved
+	Type  string          `json:"type"`
+	Room  string          `json:"room,omitempty"`
+	To    string          `json:"to,omitempty"`
+	From  string          `json:"from,omitempty"`  // sender's client ID; set by the server, never trusted from clients
+	User  *User           `json:"user,omitempty"`  // sender's identity; set by the server like From
+	Event string          `json:"event,omitempty"` // the kind of Event, e.g. "stock.changed"
+	Data  json.RawMessage `json:"data,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// roomName is what room names may look like, e.g. "lobby" or "album:42".
+var roomName = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// ValidRoom reports whether name can be used as a room name.
+func ValidRoom(name string) bool {
+	return roomName.MatchString(name)
+}
+
+// errorMessage builds an Error message.
+func errorMessage(text string) Message {
+	data, _ := json.Marshal(text)
+	return Message{Type: TypeError, Data: data}
+}
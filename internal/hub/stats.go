@@ -0,0 +1,36 @@
+package hub
+
+import "sync/atomic"
+
+// Stats are counters describing a hub's traffic since it was created.
+type Stats struct {
+	OpenConnections  int   `json:"open_connections"`
+	TotalConnections int64 `json:"total_connections"`
+	MessagesReceived int64 `json:"messages_received"`
+	MessagesSent     int64 `json:"messages_sent"`
+	DroppedMessages  int64 `json:"dropped_messages"` // lost to a full queue or refused for exceeding the rate limit
+	SlowClients      int64 `json:"slow_clients"`     // disconnected for not keeping up
+	RateLimited      int64 `json:"rate_limited"`     // messages refused for exceeding the rate limit
+	OversizedClosed  int64 `json:"oversized_closed"` // connections closed for sending a too large message
+	TimedOut         int64 `json:"timed_out"`        // connections closed for not answering pings or blocking writes
+}
+
+// counters are the hub's live Stats.
+type counters struct {
+	total, received, sent, dropped, slow, rateLimited, oversized, timedOut atomic.Int64
+}
+
+// Stats returns a snapshot of the hub's counters.
+func (h *Hub) Stats() Stats {
+	return Stats{
+		OpenConnections:  h.Clients(),
+		TotalConnections: h.stats.total.Load(),
+		MessagesReceived: h.stats.received.Load(),
+		MessagesSent:     h.stats.sent.Load(),
+		DroppedMessages:  h.stats.dropped.Load(),
+		SlowClients:      h.stats.slow.Load(),
+		RateLimited:      h.stats.rateLimited.Load(),
+		OversizedClosed:  h.stats.oversized.Load(),
+		TimedOut:         h.stats.timedOut.Load(),
+	}
+}
@@ -2,8 +2,10 @@ package models
 
 type Album struct {
 	ID       int64   `json:"id"`
-	Title    string  `json:"title"`
-	Artist   string  `json:"artist"`
-	Price    float32 `json:"price"`
-	Quantity int64   `json:"quantity"`
+	Title    string  `json:"title" validate:"trim,required,max=200"`
+	Artist   string  `json:"artist" validate:"trim,required,max=100"` // artist name (joined from the artist table)
+	ArtistID int64   `json:"artist_id"`                               // foreign key to artist.id
+	Price    float32 `json:"price" validate:"min=0"`
+	Quantity int64   `json:"quantity" validate:"min=0"`
+	Version  int64   `json:"version"` // incremented on every update (used for ETag / If-Match)
 }
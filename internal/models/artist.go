@@ -0,0 +1,6 @@
+package models
+
+type Artist struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
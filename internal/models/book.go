@@ -1,8 +1,11 @@
 package models
 
 type Book struct {
-	ID     int     `json:"id"`
-	Title  string  `json:"title"`
-	Author string  `json:"author"`
-	Price  float64 `json:"price"`
+	ID        int     `json:"id"`
+	Title     string  `json:"title" validate:"trim,required,max=200"`
+	Author    string  `json:"author" validate:"trim,max=100"` // display byline, e.g. "Sarah Vaughan and Clifford Brown"
+	Price     float64 `json:"price" validate:"min=0"`
+	AuthorIDs []int   `json:"author_ids"` // many-to-many link to Author
+	GenreIDs  []int   `json:"genre_ids"`  // many-to-many link to Genre
+	Version   int     `json:"version"`    // incremented on every update (used for ETag / If-Match)
 }
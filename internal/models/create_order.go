@@ -1,7 +1,7 @@
 package models
 
 type OrderRequest struct {
-	AlbumID  int64 `json:"album_id"`
-	Quantity int64 `json:"quantity"`
-	Customer int64 `json:"customer_id"`
+	AlbumID  int64 `json:"album_id" validate:"min=1"`
+	Quantity int64 `json:"quantity" validate:"min=1"`
+	Customer int64 `json:"customer_id"` // ignored: orders are placed for the logged-in user
 }
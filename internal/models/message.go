@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Message is a chat message broadcast to a WebSocket room.
+type Message struct {
+	ID        int64     `json:"id"`
+	Room      string    `json:"room"`
+	ClientID  string    `json:"client_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Data      string    `json:"data"` // the message's JSON payload
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,192 @@
+package pathfinder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Cell values in a grid. Free cells cost 1 to enter and walls can't be entered.
+// Any value of 2 or more is weighted terrain costing that much to enter,
+// so the original 0/1 grids work unchanged with every algorithm.
+const (
+	Free = 0
+	Wall = 1
+)
+
+// ErrInvalidPoint is returned when a start or goal is outside the grid or on a wall.
+var ErrInvalidPoint = errors.New("point is out of bounds or on a wall")
+
+// Point is a grid cell: X is the row and Y the column, as in ShortestPathBFS.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// CornerRule decides whether a diagonal move may pass the corner of a wall.
+type CornerRule string
+
+const (
+	// CornersNever forbids diagonal moves next to any wall (the default).
+	CornersNever CornerRule = "never"
+	// CornersNoSqueeze allows cutting one wall corner but not squeezing between two.
+	CornersNoSqueeze CornerRule = "no-squeeze"
+	// CornersAlways allows any diagonal move into a free cell.
+	CornersAlways CornerRule = "always"
+)
+
+// ParseCornerRule validates a corner rule name; "" means CornersNever.
+func ParseCornerRule(name string) (CornerRule, error) {
+	switch r := CornerRule(name); r {
+	case "":
+		return CornersNever, nil
+	case CornersNever, CornersNoSqueeze, CornersAlways:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown corner rule %q", name)
+	}
+}
+
+// Options configures a search. The zero value means 4-directional movement.
+type Options struct {
+	Diagonal  bool       // allow 8-directional movement (diagonal steps cost √2 × the cell cost)
+	Corners   CornerRule // how diagonal moves treat wall corners (default CornersNever)
+	Heuristic Heuristic  // A* only (default Octile with diagonals, Manhattan without)
+
+	// OnExpand, if set, is called with each node as the search expands it,
+	// e.g. to animate the search. It must not modify the grid.
+	OnExpand func(Point)
+}
+
+// expand reports an expanded node to opts.OnExpand.
+func (opts Options) expand(p Point) {
+	if opts.OnExpand != nil {
+		opts.OnExpand(p)
+	}
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	Algorithm     string  `json:"algorithm"`
+	Found         bool    `json:"found"`
+	Path          []Point `json:"path"`   // start to goal inclusive; empty if not found
+	Length        int     `json:"length"` // number of moves; -1 if not found
+	Cost          float64 `json:"cost"`   // sum of move costs along the path
+	NodesExpanded int     `json:"nodes_expanded"`
+}
+
+// straight and diagonal are the row/column offsets of the possible moves.
+var (
+	straight = [][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+	diagonal = [][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+)
+
+// inBounds reports whether p is inside the grid.
+func inBounds(grid [][]int, p Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < len(grid) && p.Y < len(grid[p.X])
+}
+
+// walkable reports whether p is inside the grid and not a wall.
+func walkable(grid [][]int, p Point) bool {
+	return inBounds(grid, p) && grid[p.X][p.Y] != Wall
+}
+
+// cellCost is the cost of entering a walkable cell.
+func cellCost(grid [][]int, p Point) float64 {
+	if v := grid[p.X][p.Y]; v > Wall {
+		return float64(v)
+	}
+	return 1
+}
+
+// checkPoints validates the start and goal of a search.
+func checkPoints(grid [][]int, start, goal Point) error {
+	if !walkable(grid, start) || !walkable(grid, goal) {
+		return ErrInvalidPoint
+	}
+	return nil
+}
+
+// move is a step to a neighbouring cell and its cost.
+type move struct {
+	to   Point
+	cost float64
+}
+
+// neighbours returns the moves possible from p under opts.
+func neighbours(grid [][]int, p Point, opts Options) []move {
+	moves := make([]move, 0, 8)
+	for _, d := range straight {
+		n := Point{p.X + d[0], p.Y + d[1]}
+		if walkable(grid, n) {
+			moves = append(moves, move{n, cellCost(grid, n)})
+		}
+	}
+	if !opts.Diagonal {
+		return moves
+	}
+	for _, d := range diagonal {
+		n := Point{p.X + d[0], p.Y + d[1]}
+		if !walkable(grid, n) {
+			continue
+		}
+		// The two orthogonal cells the diagonal move passes between
+		sideA := walkable(grid, Point{p.X + d[0], p.Y})
+		sideB := walkable(grid, Point{p.X, p.Y + d[1]})
+		switch opts.Corners {
+		case CornersAlways:
+		case CornersNoSqueeze:
+			if !sideA && !sideB {
+				continue
+			}
+		default: // CornersNever
+			if !sideA || !sideB {
+				continue
+			}
+		}
+		moves = append(moves, move{n, cellCost(grid, n) * math.Sqrt2})
+	}
+	return moves
+}
+
+// buildResult turns a parent map into a Result by walking back from goal.
+func buildResult(algorithm string, parent map[Point]Point, start, goal Point, cost float64, expanded int) Result {
+	if _, ok := parent[goal]; !ok && start != goal {
+		return notFound(algorithm, expanded)
+	}
+	path := []Point{goal}
+	for p := goal; p != start; {
+		p = parent[p]
+		path = append(path, p)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return Result{
+		Algorithm:     algorithm,
+		Found:         true,
+		Path:          path,
+		Length:        len(path) - 1,
+		Cost:          cost,
+		NodesExpanded: expanded,
+	}
+}
+
+// notFound is the Result of a search that couldn't reach the goal.
+func notFound(algorithm string, expanded int) Result {
+	return Result{Algorithm: algorithm, Path: []Point{}, Length: -1, NodesExpanded: expanded}
+}
+
+// PathCost returns the total cost of walking path (each move costs the entered
+// cell's cost, times √2 for diagonal moves).
+func PathCost(grid [][]int, path []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		c := cellCost(grid, path[i])
+		if path[i].X != path[i-1].X && path[i].Y != path[i-1].Y {
+			c *= math.Sqrt2
+		}
+		total += c
+	}
+	return total
+}
@@ -0,0 +1,162 @@
+// Package maze generates grids for the pathfinder package: perfect mazes
+// (recursive backtracker and Prim's algorithm) and randomly scattered obstacles.
+// Every generator takes a seed, so the same parameters always give the same grid.
+package maze
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+)
+
+// Generator names accepted by Generate.
+const (
+	Backtracker = "backtracker"
+	Prim        = "prim"
+	Random      = "random"
+)
+
+// Kinds lists the generators in the order they're usually shown.
+var Kinds = []string{Backtracker, Prim, Random}
+
+// Maze is a generated grid with the start and end cells benchmarks should use.
+type Maze struct {
+	Grid  [][]int          `json:"grid"`
+	Start pathfinder.Point `json:"start"`
+	End   pathfinder.Point `json:"end"`
+}
+
+// Generate builds a rows × cols grid with the named generator. density (0-1) is
+// the share of walls for Random and is ignored by the maze generators.
+func Generate(kind string, rows, cols int, density float64, seed int64) (Maze, error) {
+	if rows < 2 || cols < 2 || rows > pathfinder.MaxGridSide || cols > pathfinder.MaxGridSide {
+		return Maze{}, fmt.Errorf("size must be between 2x2 and %dx%d", pathfinder.MaxGridSide, pathfinder.MaxGridSide)
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	switch kind {
+	case Backtracker:
+		return NewBacktracker(rows, cols, rng), nil
+	case Prim:
+		return NewPrim(rows, cols, rng), nil
+	case Random:
+		if density < 0 || density > 1 {
+			return Maze{}, fmt.Errorf("density must be between 0 and 1")
+		}
+		return NewRandom(rows, cols, density, rng), nil
+	default:
+		return Maze{}, fmt.Errorf("unknown maze type %q", kind)
+	}
+}
+
+// NewBacktracker carves a perfect maze (exactly one path between any two cells)
+// with a depth-first recursive backtracker, which gives long, winding corridors.
+//
+// Rooms sit on even rows and columns and the cells between them are walls until
+// carved, so with an even size the last row or column stays solid.
+func NewBacktracker(rows, cols int, rng *rand.Rand) Maze {
+	m := solid(rows, cols)
+	start := pathfinder.Point{}
+	m.Grid[0][0] = pathfinder.Free
+
+	// An explicit stack instead of recursion, so 256×256 mazes don't grow the call stack
+	stack := []pathfinder.Point{start}
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		next := uncarvedRooms(m.Grid, curr)
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		n := next[rng.Intn(len(next))]
+		carve(m.Grid, curr, n)
+		stack = append(stack, n)
+	}
+	return m
+}
+
+// NewPrim carves a perfect maze with randomized Prim's algorithm, which grows the
+// maze outwards from the start and gives many short dead ends.
+func NewPrim(rows, cols int, rng *rand.Rand) Maze {
+	m := solid(rows, cols)
+	m.Grid[0][0] = pathfinder.Free
+
+	// Each frontier entry is an uncarved room and the carved room it would join
+	type edge struct{ from, to pathfinder.Point }
+	var frontier []edge
+	addFrontier := func(p pathfinder.Point) {
+		for _, n := range uncarvedRooms(m.Grid, p) {
+			frontier = append(frontier, edge{p, n})
+		}
+	}
+	addFrontier(pathfinder.Point{})
+
+	for len(frontier) > 0 {
+		i := rng.Intn(len(frontier))
+		e := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		if m.Grid[e.to.X][e.to.Y] == pathfinder.Free {
+			continue // already joined through another edge
+		}
+		carve(m.Grid, e.from, e.to)
+		addFrontier(e.to)
+	}
+	return m
+}
+
+// NewRandom scatters walls over an open grid so that about density of the cells
+// are blocked. The start (top-left) and end (bottom-right) are always free, but
+// unlike the mazes there's no guarantee that a path between them exists.
+func NewRandom(rows, cols int, density float64, rng *rand.Rand) Maze {
+	m := Maze{
+		Grid: make([][]int, rows),
+		End:  pathfinder.Point{X: rows - 1, Y: cols - 1},
+	}
+	for x := range m.Grid {
+		m.Grid[x] = make([]int, cols)
+		for y := range m.Grid[x] {
+			if rng.Float64() < density {
+				m.Grid[x][y] = pathfinder.Wall
+			}
+		}
+	}
+	m.Grid[0][0] = pathfinder.Free
+	m.Grid[rows-1][cols-1] = pathfinder.Free
+	return m
+}
+
+// solid returns an all-wall maze whose end is the room furthest from the top-left.
+func solid(rows, cols int) Maze {
+	m := Maze{
+		Grid: make([][]int, rows),
+		End:  pathfinder.Point{X: (rows - 1) &^ 1, Y: (cols - 1) &^ 1},
+	}
+	for x := range m.Grid {
+		m.Grid[x] = make([]int, cols)
+		for y := range m.Grid[x] {
+			m.Grid[x][y] = pathfinder.Wall
+		}
+	}
+	return m
+}
+
+// uncarvedRooms returns the rooms two cells away from p that are still walls.
+func uncarvedRooms(grid [][]int, p pathfinder.Point) []pathfinder.Point {
+	var rooms []pathfinder.Point
+	for _, d := range [][2]int{{0, -2}, {0, 2}, {-2, 0}, {2, 0}} {
+		n := pathfinder.Point{X: p.X + d[0], Y: p.Y + d[1]}
+		if n.X >= 0 && n.Y >= 0 && n.X < len(grid) && n.Y < len(grid[0]) && grid[n.X][n.Y] == pathfinder.Wall {
+			rooms = append(rooms, n)
+		}
+	}
+	return rooms
+}
+
+// carve opens room to and the wall between it and from.
+func carve(grid [][]int, from, to pathfinder.Point) {
+	grid[(from.X+to.X)/2][(from.Y+to.Y)/2] = pathfinder.Free
+	grid[to.X][to.Y] = pathfinder.Free
+}
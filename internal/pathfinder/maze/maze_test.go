@@ -0,0 +1,170 @@
+package maze
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/pathfinder"
+)
+
+func TestGenerateIsReproducible(t *testing.T) {
+	for _, kind := range Kinds {
+		a, err := Generate(kind, 21, 30, 0.3, 42)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", kind, err)
+		}
+		b, _ := Generate(kind, 21, 30, 0.3, 42)
+		c, _ := Generate(kind, 21, 30, 0.3, 43)
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s: same seed gave different grids", kind)
+		}
+		if reflect.DeepEqual(a, c) {
+			t.Errorf("%s: different seeds gave the same grid", kind)
+		}
+		if err := pathfinder.ValidateGrid(a.Grid); err != nil {
+			t.Errorf("%s: invalid grid: %v", kind, err)
+		}
+	}
+}
+
+func TestMazesArePerfect(t *testing.T) {
+	for _, kind := range []string{Backtracker, Prim} {
+		for _, size := range [][2]int{{2, 2}, {9, 9}, {20, 31}} {
+			m, _ := Generate(kind, size[0], size[1], 0, 1)
+
+			// A perfect maze is a tree: every room is reachable and the
+			// number of open cells is rooms + (rooms - 1) connecting cells.
+			rooms, open := ((size[0]+1)/2)*((size[1]+1)/2), 0
+			for x, row := range m.Grid {
+				for y, v := range row {
+					if v == pathfinder.Free {
+						open++
+						if x%2 == 1 && y%2 == 1 {
+							t.Errorf("%s %v: odd/odd cell %d,%d is open", kind, size, x, y)
+						}
+					}
+				}
+			}
+			if open != 2*rooms-1 {
+				t.Errorf("%s %v: got %d open cells, want %d", kind, size, open, 2*rooms-1)
+			}
+			res, err := pathfinder.BFS(m.Grid, m.Start, m.End, pathfinder.Options{})
+			if err != nil || !res.Found {
+				t.Errorf("%s %v: end %v not reachable: %v", kind, size, m.End, err)
+			}
+		}
+	}
+}
+
+func TestRandomDensity(t *testing.T) {
+	m, _ := Generate(Random, 100, 100, 0.25, 7)
+	walls := 0
+	for _, row := range m.Grid {
+		for _, v := range row {
+			if v == pathfinder.Wall {
+				walls++
+			}
+		}
+	}
+	if walls < 2200 || walls > 2800 {
+		t.Errorf("expected about 2500 walls at density 0.25, got %d", walls)
+	}
+	if m.Grid[0][0] != pathfinder.Free || m.Grid[99][99] != pathfinder.Free {
+		t.Errorf("start and end must be free")
+	}
+
+	if full, _ := Generate(Random, 5, 5, 1, 7); full.Grid[0][0] != pathfinder.Free {
+		t.Errorf("start must be free even at density 1")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		kind       string
+		rows, cols int
+		density    float64
+	}{
+		{"spiral", 10, 10, 0},
+		{Prim, 1, 10, 0},
+		{Backtracker, 10, pathfinder.MaxGridSide + 1, 0},
+		{Random, 10, 10, 1.5},
+	}
+	for _, tt := range tests {
+		if _, err := Generate(tt.kind, tt.rows, tt.cols, tt.density, 1); err == nil {
+			t.Errorf("expected an error for %+v", tt)
+		}
+	}
+}
+
+// BenchmarkAlgorithms sweeps grid size, density and maze type across every
+// algorithm, e.g. go test -bench . ./internal/pathfinder/maze
+// Brute force only runs on the 8×8 grids; beyond that it hits its search limit.
+func BenchmarkAlgorithms(b *testing.B) {
+	type grid struct {
+		kind    string
+		density float64
+	}
+	grids := []grid{
+		{Random, 0}, {Random, 0.1}, {Random, 0.2}, {Random, 0.3},
+		{Backtracker, 0}, {Prim, 0},
+	}
+
+	for _, size := range []int{8, 32, 64, 128} {
+		for _, g := range grids {
+			m, err := Generate(g.kind, size, size, g.density, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, name := range pathfinder.AlgorithmNames {
+				if name == "brute" && size > 8 {
+					continue
+				}
+				label := g.kind
+				if g.kind == Random {
+					label = fmt.Sprintf("random-%.1f", g.density)
+				}
+				search := pathfinder.Algorithms[name]
+				b.Run(fmt.Sprintf("%s/%dx%d/%s", name, size, size, label), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						if _, err := search(m.Grid, m.Start, m.End, pathfinder.Options{}); err != nil {
+							b.Skip(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkShortestPath sweeps grid size and density for the original
+// ShortestPathBruteForce and ShortestPathBFS. Brute force has no search limit
+// and tries every path, so it only runs on grids up to 6×6.
+func BenchmarkShortestPath(b *testing.B) {
+	for _, size := range []int{6, 8, 32, 64, 128} {
+		for _, density := range []float64{0, 0.1, 0.2, 0.3} {
+			m, err := Generate(Random, size, size, density, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			start, end := m.Start, m.End
+			label := fmt.Sprintf("%dx%d/random-%.1f", size, size, density)
+			if size <= 6 {
+				b.Run("bruteforce/"+label, func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						visited := make([][]bool, size)
+						for row := range visited {
+							visited[row] = make([]bool, size)
+						}
+						pathfinder.ShortestPathBruteForce(m.Grid, start.X, start.Y, end.X, end.Y, visited)
+					}
+				})
+			}
+			b.Run("bfs/"+label, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					pathfinder.ShortestPathBFS(m.Grid, start.X, start.Y, end.X, end.Y)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Query is one start/goal pair of a batch or multi-agent request.
+type Query struct {
+	Start Point `json:"start"`
+	Goal  Point `json:"end"`
+}
+
+// BatchResult is the outcome of one query in a batch; Err is set instead of
+// Result when the query was invalid or cancelled.
+type BatchResult struct {
+	Result
+	Err error `json:"-"`
+}
+
+// Batch runs search for every query on a pool of workers goroutines and returns
+// the results in query order. The grid is only read, so it's shared by all
+// workers; opts.OnExpand must be nil or safe for concurrent use. Queries not
+// started when ctx is cancelled get ctx.Err().
+func Batch(ctx context.Context, grid [][]int, queries []Query, search SearchFunc, opts Options, workers int) []BatchResult {
+	results := make([]BatchResult, len(queries))
+	workers = max(1, min(workers, len(queries)))
+
+	jobs := make(chan int, len(queries))
+	var wg sync.WaitGroup
+
+	// Each worker writes only to the results of the queries it takes, so no locking is needed
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				q := queries[i]
+				res, err := search(grid, q.Start, q.Goal, opts)
+				results[i] = BatchResult{Result: res, Err: err}
+			}
+		}()
+	}
+
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// NearestTarget finds, for each start, the path with the fewest moves to
+// whichever target is closest (cell weights are ignored, as in BFS). It runs one
+// multi-source BFS outwards from all targets at once, so the cost doesn't grow
+// with the number of starts. Each Result's path ends at the chosen target;
+// NodesExpanded is that of the shared search.
+func NearestTarget(grid [][]int, starts, targets []Point, opts Options) ([]Result, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no targets given")
+	}
+	for _, p := range append(append([]Point{}, starts...), targets...) {
+		if !walkable(grid, p) {
+			return nil, ErrInvalidPoint
+		}
+	}
+
+	// Moves are symmetric (corner rules included), so searching from the targets
+	// finds the same paths as searching towards them. next points one step closer.
+	_, next, expanded := bfsFrom(grid, targets, opts)
+
+	results := make([]Result, len(starts))
+	for i, start := range starts {
+		if _, reached := next[start]; !reached {
+			results[i] = notFound("nearest", expanded)
+			continue
+		}
+		path := []Point{start}
+		for p := start; next[p] != p; {
+			p = next[p]
+			path = append(path, p)
+		}
+		results[i] = Result{
+			Algorithm:     "nearest",
+			Found:         true,
+			Path:          path,
+			Length:        len(path) - 1,
+			Cost:          PathCost(grid, path),
+			NodesExpanded: expanded,
+		}
+	}
+	return results, nil
+}
+
+// bfsFrom runs a breadth-first search from all sources at once and returns each
+// reached cell's distance in moves and its parent (a source is its own parent).
+func bfsFrom(grid [][]int, sources []Point, opts Options) (map[Point]int, map[Point]Point, int) {
+	dist := map[Point]int{}
+	parent := map[Point]Point{}
+	queue := []Point{}
+	for _, s := range sources {
+		if _, seen := dist[s]; !seen {
+			dist[s], parent[s] = 0, s
+			queue = append(queue, s)
+		}
+	}
+
+	expanded := 0
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		expanded++
+		for _, m := range neighbours(grid, curr, opts) {
+			if _, seen := dist[m.to]; !seen {
+				dist[m.to], parent[m.to] = dist[curr]+1, curr
+				queue = append(queue, m.to)
+			}
+		}
+	}
+	return dist, parent, expanded
+}
+
+// MaxCooperativeExpansions caps the space-time search of all the agents of
+// one Cooperative call together; agents left without budget are reported as
+// not found.
+const MaxCooperativeExpansions = 1_000_000
+
+// cancelCheckEvery is how many expansions spaceTimeAStar makes between
+// checks of its context.
+const cancelCheckEvery = 1024
+
+// ErrAgentConflict is returned when two agents share a start or a goal.
+var ErrAgentConflict = errors.New("agents must have distinct starts and goals")
+
+// Cooperative plans paths for several agents that move at the same time, one
+// cell (or a wait) per time step, without ever colliding: no two agents are in
+// the same cell at the same time or swap cells in one step, and an agent that
+// has arrived stays on its goal.
+//
+// Agents are planned in order (prioritized planning). Each path is found with a
+// space-time A* that avoids the cells and moves reserved by the agents before
+// it, then its own cells and moves are reserved. This is fast but not complete:
+// a later agent can be boxed in by earlier ones and is then reported as not
+// found, even if a different order would have worked.
+//
+// Paths are indexed by time step, so a wait repeats the previous cell; Length
+// is the arrival time and Cost counts a wait like a move into the same cell.
+// The search stops with ctx.Err() once ctx is cancelled.
+func Cooperative(ctx context.Context, grid [][]int, agents []Query, opts Options) ([]Result, error) {
+	starts, goals := map[Point]bool{}, map[Point]bool{}
+	for _, a := range agents {
+		if err := checkPoints(grid, a.Start, a.Goal); err != nil {
+			return nil, err
+		}
+		if starts[a.Start] || goals[a.Goal] {
+			return nil, ErrAgentConflict
+		}
+		starts[a.Start], goals[a.Goal] = true, true
+	}
+
+	res := newReservations()
+	results := make([]Result, len(agents))
+	budget := MaxCooperativeExpansions
+	for i, a := range agents {
+		path, expanded, err := spaceTimeAStar(ctx, grid, a.Start, a.Goal, opts, res, budget)
+		if err != nil {
+			return nil, err
+		}
+		budget -= expanded
+		if path == nil {
+			results[i] = notFound("cooperative", expanded)
+			continue
+		}
+		res.reserve(path)
+		results[i] = Result{
+			Algorithm:     "cooperative",
+			Found:         true,
+			Path:          path,
+			Length:        len(path) - 1,
+			Cost:          PathCost(grid, path),
+			NodesExpanded: expanded,
+		}
+	}
+	return results, nil
+}
+
+// spaceTime is a cell at a time step.
+type spaceTime struct {
+	p Point
+	t int
+}
+
+// stepAt is a move from one cell to another between time t and t+1.
+type stepAt struct {
+	from, to Point
+	t        int
+}
+
+// reservations records the cells and moves claimed by already planned agents.
+type reservations struct {
+	cells  map[spaceTime]bool
+	moves  map[stepAt]bool
+	parked map[Point]int // goal cell → time its agent arrives and stays
+	last   map[Point]int // latest time each cell is reserved
+	end    int           // latest reserved time overall
+}
+
+func newReservations() *reservations {
+	return &reservations{
+		cells:  map[spaceTime]bool{},
+		moves:  map[stepAt]bool{},
+		parked: map[Point]int{},
+		last:   map[Point]int{},
+	}
+}
+
+// reserve claims a planned path.
+func (r *reservations) reserve(path []Point) {
+	for t, p := range path {
+		r.cells[spaceTime{p, t}] = true
+		r.last[p] = max(r.last[p], t)
+		if t > 0 {
+			r.moves[stepAt{path[t-1], p, t - 1}] = true
+		}
+	}
+	arrival := len(path) - 1
+	r.parked[path[arrival]] = arrival
+	r.end = max(r.end, arrival)
+}
+
+// free reports whether an agent may move from -> to between t and t+1.
+func (r *reservations) free(from, to Point, t int) bool {
+	if r.cells[spaceTime{to, t + 1}] || r.moves[stepAt{to, from, t}] {
+		return false // occupied, or the agent there is moving the other way (a swap)
+	}
+	if arrived, ok := r.parked[to]; ok && t+1 >= arrived {
+		return false
+	}
+	return true
+}
+
+// spaceTimeAStar finds the earliest collision-free arrival at goal given the
+// reservations, or nil, in at most budget expansions. The heuristic is the
+// exact move count on the empty grid.
+func spaceTimeAStar(ctx context.Context, grid [][]int, start, goal Point, opts Options, res *reservations, budget int) ([]Point, int, error) {
+	if budget <= 0 {
+		return nil, 0, nil
+	}
+	dist, _, _ := bfsFrom(grid, []Point{goal}, opts)
+	if _, ok := dist[start]; !ok {
+		return nil, 0, nil
+	}
+	if arrived, ok := res.parked[start]; ok && arrived == 0 {
+		return nil, 0, nil // another agent is already parked here
+	}
+
+	// Later than this, every earlier agent is parked and the grid no longer changes
+	horizon := res.end + len(grid)*len(grid[0])
+
+	parent := map[spaceTime]spaceTime{}
+	closed := map[spaceTime]bool{}
+	first := spaceTime{start, 0}
+	open := &stateQueue{{s: first, priority: dist[start]}}
+	expanded := 0
+
+	for open.Len() > 0 && expanded < budget {
+		if expanded%cancelCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, expanded, err
+			}
+		}
+		curr := heap.Pop(open).(stateNode).s
+		if closed[curr] {
+			continue
+		}
+		closed[curr] = true
+		expanded++
+		opts.expand(curr.p)
+
+		if curr.p == goal && curr.t > res.lastAt(goal) {
+			path := make([]Point, curr.t+1)
+			for s := curr; ; s = parent[s] {
+				path[s.t] = s.p
+				if s == first {
+					break
+				}
+			}
+			return path, expanded, nil
+		}
+		if curr.t >= horizon {
+			continue
+		}
+
+		// Waiting in place is always a candidate move
+		candidates := append(neighbours(grid, curr.p, opts), move{to: curr.p})
+		for _, m := range candidates {
+			next := spaceTime{m.to, curr.t + 1}
+			if closed[next] || !res.free(curr.p, m.to, curr.t) {
+				continue
+			}
+			d, ok := dist[m.to]
+			if !ok {
+				continue
+			}
+			if _, seen := parent[next]; !seen {
+				parent[next] = curr
+				heap.Push(open, stateNode{s: next, priority: next.t + d})
+			}
+		}
+	}
+	return nil, expanded, nil
+}
+
+// lastAt is the latest time p is reserved, or -1.
+func (r *reservations) lastAt(p Point) int {
+	if t, ok := r.last[p]; ok {
+		return t
+	}
+	return -1
+}
+
+// stateNode is an entry in the space-time A* open set.
+type stateNode struct {
+	s        spaceTime
+	priority int
+}
+
+// stateQueue is a min-heap of stateNodes ordered by priority (container/heap).
+type stateQueue []stateNode
+
+func (q stateQueue) Len() int           { return len(q) }
+func (q stateQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q stateQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *stateQueue) Push(x any)        { *q = append(*q, x.(stateNode)) }
+func (q *stateQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
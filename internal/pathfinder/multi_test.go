@@ -0,0 +1,158 @@
+package pathfinder
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	queries := []Query{
+		{Point{0, 0}, Point{7, 7}},
+		{Point{7, 0}, Point{0, 7}},
+		{Point{0, 0}, Point{0, 2}}, // goal on a wall
+		{Point{4, 4}, Point{4, 4}},
+	}
+	results := Batch(context.Background(), sampleGrid, queries, AStar, Options{}, 3)
+	if len(results) != len(queries) {
+		t.Fatalf("got %d results for %d queries", len(results), len(queries))
+	}
+	for i, q := range queries {
+		want, wantErr := AStar(sampleGrid, q.Start, q.Goal, Options{})
+		if results[i].Err != wantErr || results[i].Length != want.Length {
+			t.Errorf("query %d: got %+v, want %+v, %v", i, results[i], want, wantErr)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, r := range Batch(ctx, sampleGrid, queries, BFS, Options{}, 2) {
+		if r.Err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", r.Err)
+		}
+	}
+}
+
+func TestNearestTarget(t *testing.T) {
+	grid := [][]int{
+		{0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 0},
+		{0, 0, 0, 1, 0},
+		{1, 1, 0, 1, 0},
+	}
+	targets := []Point{{3, 2}, {3, 4}}
+	starts := []Point{{0, 0}, {0, 4}, {2, 2}, {3, 2}}
+
+	results, err := NearestTarget(grid, starts, targets, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantEnd := []Point{{3, 2}, {3, 4}, {3, 2}, {3, 2}}
+	wantLen := []int{5, 3, 1, 0}
+	for i, res := range results {
+		if !res.Found || res.Path[len(res.Path)-1] != wantEnd[i] || res.Length != wantLen[i] {
+			t.Errorf("start %v: got %+v, want %d moves to %v", starts[i], res, wantLen[i], wantEnd[i])
+			continue
+		}
+		checkPath(t, grid, res, starts[i], wantEnd[i])
+	}
+
+	walled := [][]int{{0, 1, 0}}
+	if res, _ := NearestTarget(walled, []Point{{0, 0}}, []Point{{0, 2}}, Options{}); res[0].Found {
+		t.Errorf("expected no path, got %+v", res[0])
+	}
+	if _, err := NearestTarget(grid, starts, nil, Options{}); err == nil {
+		t.Errorf("expected an error without targets")
+	}
+	if _, err := NearestTarget(grid, []Point{{1, 1}}, targets, Options{}); err != ErrInvalidPoint {
+		t.Errorf("expected ErrInvalidPoint, got %v", err)
+	}
+}
+
+// checkNoCollisions verifies that agents never share a cell or swap cells,
+// treating agents that have arrived as staying on their goal.
+func checkNoCollisions(t *testing.T, results []Result) {
+	t.Helper()
+	at := func(r Result, step int) Point {
+		return r.Path[min(step, len(r.Path)-1)]
+	}
+	end := 0
+	for _, r := range results {
+		end = max(end, len(r.Path))
+	}
+	for step := 0; step < end; step++ {
+		for i := range results {
+			for j := i + 1; j < len(results); j++ {
+				a, b := results[i], results[j]
+				if at(a, step) == at(b, step) {
+					t.Fatalf("agents %d and %d collide at %v, step %d", i, j, at(a, step), step)
+				}
+				if step > 0 && at(a, step) == at(b, step-1) && at(b, step) == at(a, step-1) {
+					t.Fatalf("agents %d and %d swap cells at step %d", i, j, step)
+				}
+			}
+		}
+	}
+}
+
+func TestCooperative(t *testing.T) {
+	// Two agents swap ends of a corridor; the second has to wait in the side pocket
+	corridor := [][]int{
+		{0, 0, 0, 0, 0},
+		{1, 1, 1, 0, 1},
+	}
+	agents := []Query{
+		{Point{0, 0}, Point{0, 4}},
+		{Point{0, 4}, Point{0, 0}},
+	}
+	results, err := Cooperative(context.Background(), corridor, agents, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, res := range results {
+		if !res.Found || res.Path[0] != agents[i].Start || res.Path[len(res.Path)-1] != agents[i].Goal {
+			t.Fatalf("agent %d: unexpected result %+v", i, res)
+		}
+	}
+	if results[0].Length != 4 || results[1].Length <= 4 {
+		t.Errorf("expected agent 0 to go straight and agent 1 to detour, got %d and %d moves",
+			results[0].Length, results[1].Length)
+	}
+	checkNoCollisions(t, results)
+
+	// Four agents crossing an open grid
+	open := make([][]int, 5)
+	for i := range open {
+		open[i] = make([]int, 5)
+	}
+	crossing := []Query{
+		{Point{2, 0}, Point{2, 4}},
+		{Point{2, 4}, Point{2, 0}},
+		{Point{0, 2}, Point{4, 2}},
+		{Point{4, 2}, Point{0, 2}},
+	}
+	results, _ = Cooperative(context.Background(), open, crossing, Options{Diagonal: true})
+	for i, res := range results {
+		if !res.Found {
+			t.Errorf("agent %d: no path", i)
+		}
+	}
+	checkNoCollisions(t, results)
+
+	// Without a pocket there's no way past, so the second agent is reported as not found
+	blocked := [][]int{{0, 0, 0}}
+	results, _ = Cooperative(context.Background(), blocked, []Query{{Point{0, 0}, Point{0, 2}}, {Point{0, 2}, Point{0, 0}}}, Options{})
+	if !results[0].Found || results[1].Found {
+		t.Errorf("expected only the first agent to find a path, got %+v", results)
+	}
+
+	if _, err := Cooperative(context.Background(), open, []Query{{Point{0, 0}, Point{1, 1}}, {Point{0, 0}, Point{2, 2}}}, Options{}); err != ErrAgentConflict {
+		t.Errorf("expected ErrAgentConflict for a shared start, got %v", err)
+	}
+
+	// A cancelled search stops with the context's error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Cooperative(ctx, open, crossing, Options{}); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package pathfinder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxGridSide is the largest number of rows or columns accepted from users.
+const MaxGridSide = 256
+
+// MaxCellCost is the most expensive weighted terrain value.
+const MaxCellCost = 9
+
+// ValidateGrid checks that a grid is non-empty, rectangular, at most
+// MaxGridSide in each direction and only holds values from Free to MaxCellCost.
+func ValidateGrid(grid [][]int) error {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return fmt.Errorf("grid is empty")
+	}
+	if len(grid) > MaxGridSide || len(grid[0]) > MaxGridSide {
+		return fmt.Errorf("grid is larger than %dx%d", MaxGridSide, MaxGridSide)
+	}
+	for x, row := range grid {
+		if len(row) != len(grid[0]) {
+			return fmt.Errorf("row %d has %d cells, expected %d", x, len(row), len(grid[0]))
+		}
+		for y, v := range row {
+			if v < Free || v > MaxCellCost {
+				return fmt.Errorf("cell %d,%d has value %d, expected %d-%d", x, y, v, Free, MaxCellCost)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseASCII reads a grid drawn as text, one row per line:
+//
+//	.  free cell        #  wall
+//	S  start (free)     E  end (free)
+//	2-9  weighted terrain
+//
+// start and end are nil if the drawing doesn't contain them.
+// The resulting grid is checked with ValidateGrid.
+func ParseASCII(text string) (grid [][]int, start, end *Point, err error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > MaxGridSide {
+		return nil, nil, nil, fmt.Errorf("grid is larger than %dx%d", MaxGridSide, MaxGridSide)
+	}
+
+	for x, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if len(line) > MaxGridSide {
+			return nil, nil, nil, fmt.Errorf("grid is larger than %dx%d", MaxGridSide, MaxGridSide)
+		}
+		row := make([]int, len(line))
+		for y, ch := range []byte(line) {
+			switch {
+			case ch == '.':
+				row[y] = Free
+			case ch == '#':
+				row[y] = Wall
+			case ch >= '2' && ch <= '9':
+				row[y] = int(ch - '0')
+			case ch == 'S' || ch == 'E':
+				p := &Point{X: x, Y: y}
+				if ch == 'S' && start == nil {
+					start = p
+				} else if ch == 'E' && end == nil {
+					end = p
+				} else {
+					return nil, nil, nil, fmt.Errorf("line %d: more than one %q", x+1, ch)
+				}
+			default:
+				return nil, nil, nil, fmt.Errorf("line %d: unexpected character %q (use . # S E or 2-9)", x+1, ch)
+			}
+		}
+		grid = append(grid, row)
+	}
+
+	if err := ValidateGrid(grid); err != nil {
+		return nil, nil, nil, err
+	}
+	return grid, start, end, nil
+}
+
+// FormatASCII draws a grid in the notation read by ParseASCII. start and end
+// may be nil; weighted cells above MaxCellCost are drawn as 9.
+func FormatASCII(grid [][]int, start, end *Point) string {
+	var b strings.Builder
+	for x, row := range grid {
+		for y, v := range row {
+			p := Point{X: x, Y: y}
+			switch {
+			case start != nil && p == *start:
+				b.WriteByte('S')
+			case end != nil && p == *end:
+				b.WriteByte('E')
+			case v == Free:
+				b.WriteByte('.')
+			case v == Wall:
+				b.WriteByte('#')
+			default:
+				b.WriteByte('0' + byte(min(v, MaxCellCost)))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package pathfinder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseASCII(t *testing.T) {
+	grid, start, end, err := ParseASCII("S.#\r\n.9.\n..E\n\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][]int{
+		{0, 0, 1},
+		{0, 9, 0},
+		{0, 0, 0},
+	}
+	if !reflect.DeepEqual(grid, want) {
+		t.Errorf("grid = %v, want %v", grid, want)
+	}
+	if *start != (Point{0, 0}) || *end != (Point{2, 2}) {
+		t.Errorf("start %v, end %v", *start, *end)
+	}
+
+	if got := FormatASCII(grid, start, end); got != "S.#\n.9.\n..E\n" {
+		t.Errorf("FormatASCII didn't round-trip: %q", got)
+	}
+
+	for _, bad := range []string{"", "S.x", "S.\n...", "SS.", strings.Repeat(".", MaxGridSide+1)} {
+		if _, _, _, err := ParseASCII(bad); err == nil {
+			t.Errorf("ParseASCII(%q) = nil error, want error", bad)
+		}
+	}
+}
+
+func TestValidateGrid(t *testing.T) {
+	if err := ValidateGrid(sampleGrid); err != nil {
+		t.Errorf("sample grid should be valid: %v", err)
+	}
+	for _, bad := range [][][]int{nil, {{}}, {{0, 0}, {0}}, {{0, -1}}, {{0, MaxCellCost + 1}}} {
+		if err := ValidateGrid(bad); err == nil {
+			t.Errorf("ValidateGrid(%v) = nil, want error", bad)
+		}
+	}
+}
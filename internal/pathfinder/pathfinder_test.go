@@ -0,0 +1,193 @@
+package pathfinder
+
+import (
+	"math"
+	"testing"
+)
+
+// sampleGrid is the grid used by the /pathfinder endpoint.
+var sampleGrid = [][]int{
+	{0, 0, 1, 0, 0, 0, 0, 0},
+	{1, 0, 0, 0, 1, 0, 1, 0},
+	{0, 0, 1, 0, 0, 0, 0, 0},
+	{0, 1, 0, 1, 0, 1, 0, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{1, 1, 0, 1, 1, 1, 0, 0},
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 1, 0, 1, 0, 1, 0, 0},
+}
+
+// checkPath verifies that a path is connected, avoids walls and has the reported length and cost.
+func checkPath(t *testing.T, grid [][]int, res Result, start, goal Point) {
+	t.Helper()
+	if res.Path[0] != start || res.Path[len(res.Path)-1] != goal {
+		t.Fatalf("%s: path %v doesn't run from %v to %v", res.Algorithm, res.Path, start, goal)
+	}
+	for i, p := range res.Path {
+		if !walkable(grid, p) {
+			t.Fatalf("%s: path goes through wall at %v", res.Algorithm, p)
+		}
+		if i > 0 {
+			prev := res.Path[i-1]
+			if abs(p.X-prev.X) > 1 || abs(p.Y-prev.Y) > 1 {
+				t.Fatalf("%s: path jumps from %v to %v", res.Algorithm, prev, p)
+			}
+		}
+	}
+	if res.Length != len(res.Path)-1 || math.Abs(res.Cost-PathCost(grid, res.Path)) > 1e-9 {
+		t.Errorf("%s: length %d / cost %v don't match path %v", res.Algorithm, res.Length, res.Cost, res.Path)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func TestAlgorithmsAgreeWithBFS(t *testing.T) {
+	start, goal := Point{0, 0}, Point{7, 7}
+	want := ShortestPathBFS(sampleGrid, start.X, start.Y, goal.X, goal.Y)
+
+	for _, name := range AlgorithmNames {
+		res, err := Algorithms[name](sampleGrid, start, goal, Options{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !res.Found || res.Length != want {
+			t.Errorf("%s: got length %d, want %d", name, res.Length, want)
+			continue
+		}
+		checkPath(t, sampleGrid, res, start, goal)
+	}
+}
+
+func TestWeightedGrid(t *testing.T) {
+	// The straight route along the top row crosses expensive terrain (9),
+	// the detour through the bottom row is longer but cheaper.
+	grid := [][]int{
+		{0, 9, 9, 0},
+		{0, 1, 1, 0},
+		{0, 0, 0, 0},
+	}
+	start, goal := Point{0, 0}, Point{0, 3}
+
+	bfs, _ := BFS(grid, start, goal, Options{})
+	if bfs.Length != 3 {
+		t.Errorf("BFS should take the fewest moves (3), got %d", bfs.Length)
+	}
+	for _, search := range []SearchFunc{Dijkstra, AStar, BruteForce} {
+		res, _ := search(grid, start, goal, Options{})
+		if res.Cost != 7 || res.Length != 7 {
+			t.Errorf("%s: expected the cheaper detour (cost 7), got %+v", res.Algorithm, res)
+		}
+		checkPath(t, grid, res, start, goal)
+	}
+}
+
+func TestDiagonalMovement(t *testing.T) {
+	grid := [][]int{
+		{0, 0, 1},
+		{0, 0, 0},
+		{1, 0, 0},
+	}
+	start, goal := Point{0, 0}, Point{2, 2}
+	for _, h := range []Heuristic{Manhattan, Euclidean, Octile} {
+		res, _ := AStar(grid, start, goal, Options{Diagonal: true, Heuristic: h})
+		if res.Length != 2 || math.Abs(res.Cost-2*math.Sqrt2) > 1e-9 {
+			t.Errorf("%s: expected two diagonal moves, got %+v", h, res)
+		}
+	}
+
+	// Cutting the wall's corner from (0,0) to (1,1) is allowed unless corners are forbidden
+	cut := [][]int{
+		{0, 1},
+		{0, 0},
+	}
+	tests := []struct {
+		rule CornerRule
+		want int
+	}{
+		{CornersNever, 2}, {CornersNoSqueeze, 1}, {CornersAlways, 1},
+	}
+	for _, tt := range tests {
+		res, _ := Dijkstra(cut, Point{0, 0}, Point{1, 1}, Options{Diagonal: true, Corners: tt.rule})
+		if res.Length != tt.want {
+			t.Errorf("corners %q: got length %d, want %d", tt.rule, res.Length, tt.want)
+		}
+	}
+
+	// Squeezing between two walls needs CornersAlways
+	squeeze := [][]int{
+		{0, 1},
+		{1, 0},
+	}
+	if res, _ := BFS(squeeze, Point{0, 0}, Point{1, 1}, Options{Diagonal: true, Corners: CornersNoSqueeze}); res.Found {
+		t.Errorf("expected no path without squeezing, got %+v", res)
+	}
+	if res, _ := BFS(squeeze, Point{0, 0}, Point{1, 1}, Options{Diagonal: true, Corners: CornersAlways}); res.Length != 1 {
+		t.Errorf("expected a single squeezing move, got %+v", res)
+	}
+}
+
+func TestAStarExpandsFewerNodes(t *testing.T) {
+	grid := make([][]int, 30)
+	for i := range grid {
+		grid[i] = make([]int, 30)
+	}
+	start, goal := Point{0, 0}, Point{29, 29}
+
+	dijkstra, _ := Dijkstra(grid, start, goal, Options{Diagonal: true})
+	astar, _ := AStar(grid, start, goal, Options{Diagonal: true})
+	if astar.Cost != dijkstra.Cost {
+		t.Errorf("A* cost %v differs from Dijkstra %v", astar.Cost, dijkstra.Cost)
+	}
+	if astar.NodesExpanded >= dijkstra.NodesExpanded {
+		t.Errorf("expected A* to expand fewer nodes: %d vs %d", astar.NodesExpanded, dijkstra.NodesExpanded)
+	}
+}
+
+func TestOnExpand(t *testing.T) {
+	start, goal := Point{0, 0}, Point{7, 7}
+	for _, name := range AlgorithmNames {
+		var visited []Point
+		res, _ := Algorithms[name](sampleGrid, start, goal, Options{
+			OnExpand: func(p Point) { visited = append(visited, p) },
+		})
+		if len(visited) != res.NodesExpanded || visited[0] != start {
+			t.Errorf("%s: got %d expansions starting at %v, want %d starting at %v",
+				name, len(visited), visited[0], res.NodesExpanded, start)
+		}
+	}
+}
+
+func TestUnreachableAndInvalid(t *testing.T) {
+	grid := [][]int{
+		{0, 1, 0},
+		{1, 1, 0},
+	}
+	for _, name := range AlgorithmNames {
+		res, err := Algorithms[name](grid, Point{0, 0}, Point{0, 2}, Options{})
+		if err != nil || res.Found || res.Length != -1 || len(res.Path) != 0 {
+			t.Errorf("%s: expected no path, got %+v, %v", name, res, err)
+		}
+		if _, err := Algorithms[name](grid, Point{0, 0}, Point{0, 1}, Options{}); err != ErrInvalidPoint {
+			t.Errorf("%s: expected ErrInvalidPoint for a goal on a wall, got %v", name, err)
+		}
+		if _, err := Algorithms[name](grid, Point{-1, 0}, Point{0, 2}, Options{}); err != ErrInvalidPoint {
+			t.Errorf("%s: expected ErrInvalidPoint for an out-of-bounds start, got %v", name, err)
+		}
+	}
+}
+
+func TestBruteForceLimit(t *testing.T) {
+	open := make([][]int, 8)
+	for i := range open {
+		open[i] = make([]int, 8)
+	}
+	_, err := BruteForce(open, Point{0, 0}, Point{7, 7}, Options{Diagonal: true, Corners: CornersAlways})
+	if err != ErrSearchLimit {
+		t.Errorf("expected ErrSearchLimit on an open 8x8 grid with diagonals, got %v", err)
+	}
+}
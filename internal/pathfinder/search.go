@@ -0,0 +1,232 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Heuristic estimates the remaining cost to the goal for A*.
+type Heuristic string
+
+const (
+	// Manhattan suits 4-directional movement (overestimates with diagonals).
+	Manhattan Heuristic = "manhattan"
+	// Euclidean is the straight-line distance; admissible for both movement modes.
+	Euclidean Heuristic = "euclidean"
+	// Octile is the exact distance on an open 8-directional grid.
+	Octile Heuristic = "octile"
+)
+
+// estimate returns h's estimate of the cost from a to b.
+// Cells cost at least 1, so these never overestimate on weighted grids.
+func (h Heuristic) estimate(a, b Point) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	switch h {
+	case Manhattan:
+		return dx + dy
+	case Euclidean:
+		return math.Hypot(dx, dy)
+	default: // Octile
+		return dx + dy + (math.Sqrt2-2)*math.Min(dx, dy)
+	}
+}
+
+// ParseHeuristic validates a heuristic name; "" lets AStar pick one.
+func ParseHeuristic(name string) (Heuristic, error) {
+	switch h := Heuristic(name); h {
+	case "", Manhattan, Euclidean, Octile:
+		return h, nil
+	default:
+		return "", fmt.Errorf("unknown heuristic %q", name)
+	}
+}
+
+// SearchFunc is the signature shared by all pathfinding algorithms.
+type SearchFunc func(grid [][]int, start, goal Point, opts Options) (Result, error)
+
+// Algorithms maps algorithm names (as used by the /pathfinder endpoint) to implementations.
+var Algorithms = map[string]SearchFunc{
+	"brute":    BruteForce,
+	"bfs":      BFS,
+	"dijkstra": Dijkstra,
+	"astar":    AStar,
+}
+
+// AlgorithmNames lists the algorithms in the order they're usually compared.
+var AlgorithmNames = []string{"brute", "bfs", "dijkstra", "astar"}
+
+// AStar finds the cheapest path using A* with opts.Heuristic.
+func AStar(grid [][]int, start, goal Point, opts Options) (Result, error) {
+	h := opts.Heuristic
+	if h == "" {
+		h = Manhattan
+		if opts.Diagonal {
+			h = Octile
+		}
+	}
+	return bestFirst("astar", grid, start, goal, opts, func(p Point) float64 {
+		return h.estimate(p, goal)
+	})
+}
+
+// Dijkstra finds the cheapest path with Dijkstra's algorithm (A* without a heuristic).
+func Dijkstra(grid [][]int, start, goal Point, opts Options) (Result, error) {
+	return bestFirst("dijkstra", grid, start, goal, opts, func(Point) float64 { return 0 })
+}
+
+// bestFirst is the shared A*/Dijkstra search: it always expands the open node
+// with the lowest cost-so-far plus heuristic.
+func bestFirst(name string, grid [][]int, start, goal Point, opts Options, h func(Point) float64) (Result, error) {
+	if err := checkPoints(grid, start, goal); err != nil {
+		return Result{}, err
+	}
+
+	cost := map[Point]float64{start: 0}
+	parent := map[Point]Point{}
+	closed := map[Point]bool{}
+	open := &nodeQueue{{p: start, priority: h(start)}}
+	expanded := 0
+
+	for open.Len() > 0 {
+		curr := heap.Pop(open).(node)
+		if closed[curr.p] {
+			continue // stale queue entry
+		}
+		closed[curr.p] = true
+		expanded++
+		opts.expand(curr.p)
+
+		if curr.p == goal {
+			return buildResult(name, parent, start, goal, cost[goal], expanded), nil
+		}
+		for _, m := range neighbours(grid, curr.p, opts) {
+			newCost := cost[curr.p] + m.cost
+			if old, seen := cost[m.to]; seen && newCost >= old {
+				continue
+			}
+			cost[m.to] = newCost
+			parent[m.to] = curr.p
+			heap.Push(open, node{p: m.to, priority: newCost + h(m.to)})
+		}
+	}
+	return notFound(name, expanded), nil
+}
+
+// BFS finds the path with the fewest moves (cell weights are ignored).
+func BFS(grid [][]int, start, goal Point, opts Options) (Result, error) {
+	if err := checkPoints(grid, start, goal); err != nil {
+		return Result{}, err
+	}
+
+	parent := map[Point]Point{}
+	visited := map[Point]bool{start: true}
+	queue := []Point{start}
+	expanded := 0
+
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		expanded++
+		opts.expand(curr)
+
+		if curr == goal {
+			res := buildResult("bfs", parent, start, goal, 0, expanded)
+			res.Cost = PathCost(grid, res.Path)
+			return res, nil
+		}
+		for _, m := range neighbours(grid, curr, opts) {
+			if !visited[m.to] {
+				visited[m.to] = true
+				parent[m.to] = curr
+				queue = append(queue, m.to)
+			}
+		}
+	}
+	return notFound("bfs", expanded), nil
+}
+
+// MaxBruteForceExpansions caps how many nodes BruteForce visits before giving up
+// with ErrSearchLimit (about a second of work).
+const MaxBruteForceExpansions = 1_000_000
+
+// ErrSearchLimit is returned when a brute-force search exceeds MaxBruteForceExpansions.
+var ErrSearchLimit = errors.New("search space too large for brute force")
+
+// BruteForce tries every simple path with depth-first backtracking (like
+// ShortestPathBruteForce) and keeps the cheapest. Its running time grows
+// exponentially with the number of free cells, so only use it on small grids.
+func BruteForce(grid [][]int, start, goal Point, opts Options) (Result, error) {
+	if err := checkPoints(grid, start, goal); err != nil {
+		return Result{}, err
+	}
+
+	visited := map[Point]bool{}
+	var path, best []Point
+	bestCost := math.Inf(1)
+	expanded := 0
+
+	var dfs func(p Point, cost float64)
+	dfs = func(p Point, cost float64) {
+		if expanded >= MaxBruteForceExpansions {
+			return
+		}
+		expanded++
+		opts.expand(p)
+		path = append(path, p)
+		defer func() { path = path[:len(path)-1] }()
+
+		if p == goal {
+			if cost < bestCost {
+				bestCost = cost
+				best = append([]Point(nil), path...)
+			}
+			return
+		}
+		visited[p] = true
+		for _, m := range neighbours(grid, p, opts) {
+			if !visited[m.to] {
+				dfs(m.to, cost+m.cost)
+			}
+		}
+		visited[p] = false
+	}
+	dfs(start, 0)
+
+	if expanded >= MaxBruteForceExpansions {
+		return Result{}, ErrSearchLimit
+	}
+	if best == nil {
+		return notFound("brute", expanded), nil
+	}
+	return Result{
+		Algorithm:     "brute",
+		Found:         true,
+		Path:          best,
+		Length:        len(best) - 1,
+		Cost:          bestCost,
+		NodesExpanded: expanded,
+	}, nil
+}
+
+// node is an entry in the A*/Dijkstra open set.
+type node struct {
+	p        Point
+	priority float64
+}
+
+// nodeQueue is a min-heap of nodes ordered by priority (container/heap).
+type nodeQueue []node
+
+func (q nodeQueue) Len() int           { return len(q) }
+func (q nodeQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x any)        { *q = append(*q, x.(node)) }
+func (q *nodeQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
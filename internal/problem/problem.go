@@ -0,0 +1,63 @@
+// Package problem writes HTTP error responses as problem details (RFC 9457):
+//
+//	{
+//	  "type": "about:blank",
+//	  "title": "Unprocessable Entity",
+//	  "status": 422,
+//	  "detail": "The request body has 1 invalid field.",
+//	  "instance": "/albums",
+//	  "errors": [{"field": "title", "rule": "max", "message": "must be at most 200 characters"}]
+//	}
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// FieldError says what's wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name, dotted for nested fields (e.g. "address.city")
+	Rule    string `json:"rule"`    // the rule that failed: "required", "max", "type", "unknown"...
+	Message string `json:"message"` // readable, without the field name (e.g. "is required")
+}
+
+// Problem is a problem details object. Errors is an extension member listing
+// every invalid field of the request.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// New creates a problem with the given status and detail. Its type is
+// "about:blank", so the title is the status text.
+func New(status int, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// Error returns the detail, so a Problem can be returned as an error.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// Write sends p as the response, with the request path as its instance if it has none.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		withPath := *p
+		withPath.Instance = r.URL.Path
+		p = &withPath
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
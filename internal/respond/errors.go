@@ -0,0 +1,107 @@
+package respond
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	assets "github.com/shahinzaman102/Go_JumpStart"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+)
+
+// errorPage renders a problem for browsers.
+var errorPage = template.Must(template.ParseFS(assets.Templates, "templates/error.html"))
+
+// statusCoder is implemented by errors that know the status of a response
+// reporting them, such as those of the data layer.
+type statusCoder interface {
+	StatusCode() int
+}
+
+// Status returns the response status for err: that of its problem if it is
+// one, its StatusCode if it has one, 504 for a timeout and 500 otherwise.
+func Status(err error) int {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p.Status
+	}
+	var sc statusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Error writes err as the response, in the format the client accepts.
+// A *problem.Problem is sent as it is; other errors become a problem with
+// their Status and message. A 500's message is logged instead of sent, so
+// database errors and the like don't reach clients.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		Problem(w, r, p)
+		return
+	}
+	status := Status(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		Problem(w, r, problem.New(status, ""))
+		return
+	}
+	Problem(w, r, problem.New(status, sentence(err.Error())))
+}
+
+// NotFound replies 404 in the format the client accepts. It can be used as
+// a router's not-found handler.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Problem(w, r, problem.New(http.StatusNotFound, ""))
+}
+
+// Problem writes p as problem details for API clients, an error page for
+// browsers or a line of text (plus one per invalid field) for the rest.
+func Problem(w http.ResponseWriter, r *http.Request, p *problem.Problem) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	VaryAccept(w)
+	switch Negotiate(r, JSON, HTML, Text) {
+	case HTML:
+		w.Header().Set("Content-Type", HTML+"; charset=utf-8")
+		w.WriteHeader(p.Status)
+		errorPage.Execute(w, p)
+	case Text:
+		w.Header().Set("Content-Type", Text+"; charset=utf-8")
+		w.WriteHeader(p.Status)
+		if p.Detail != "" {
+			fmt.Fprintf(w, "%d %s: %s\n", p.Status, p.Title, p.Detail)
+		} else {
+			fmt.Fprintf(w, "%d %s\n", p.Status, p.Title)
+		}
+		for _, e := range p.Errors {
+			fmt.Fprintf(w, "%s %s\n", e.Field, e.Message)
+		}
+	default:
+		problem.Write(w, r, p)
+	}
+}
+
+// sentence turns an error message ("book not found") into a problem
+// detail ("Book not found.").
+func sentence(msg string) string {
+	if msg == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(msg)
+	msg = string(unicode.ToUpper(first)) + msg[size:]
+	if !strings.HasSuffix(msg, ".") {
+		msg += "."
+	}
+	return msg
+}
@@ -0,0 +1,137 @@
+// Package respond writes HTTP responses in the format the client asks for
+// with its Accept header: JSON (or MessagePack) for API clients, HTML pages
+// for browsers and plain text for those that want it. Errors are sent as
+// problem details (see package problem), a templated error page or a line of text.
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Media types a response can be negotiated to.
+const (
+	JSON    = "application/json"
+	NDJSON  = "application/x-ndjson" // newline-delimited JSON, for streamed collections
+	MsgPack = "application/msgpack"
+	HTML    = "text/html"
+	Text    = "text/plain"
+)
+
+// Negotiate returns the offer the Accept header of r prefers. Without an
+// Accept header, or when it accepts none of the offers, the first offer is
+// returned: answering in a default format beats a 406 for these responses.
+// application/problem+json and other +json types count as JSON, and the
+// older application/x-msgpack as MessagePack.
+func Negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// quality returns the q value accept gives offer: that of the most specific
+// media range matching it, or 0 if none does.
+func quality(accept, offer string) float64 {
+	offerType, _, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+		s := -1
+		switch {
+		case mediaRange == offer:
+			s = 3
+		case offer == JSON && strings.HasPrefix(mediaRange, "application/") && strings.HasSuffix(mediaRange, "+json"):
+			s = 2
+		case offer == MsgPack && mediaRange == "application/x-msgpack":
+			s = 2
+		case mediaRange == offerType+"/*":
+			s = 1
+		case mediaRange == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = rangeQuality(params), s
+		}
+	}
+	return q
+}
+
+// rangeQuality returns the q parameter of a media range (1 if it has none).
+func rangeQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(name) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+// VaryAccept tells caches that the response depends on the Accept header.
+func VaryAccept(w http.ResponseWriter) {
+	for _, vary := range w.Header().Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), "Accept") {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", "Accept")
+}
+
+// WriteJSON sends v as JSON with the given status. Headers are set before the
+// status is written, so the Content-Type isn't lost.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", JSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Write sends v with the given status as JSON or, if the client prefers it,
+// as MessagePack. MessagePack uses the json struct tags, so both formats carry
+// the same field names.
+func Write(w http.ResponseWriter, r *http.Request, status int, v any) {
+	VaryAccept(w)
+	if Negotiate(r, JSON, MsgPack) != MsgPack {
+		WriteJSON(w, status, v)
+		return
+	}
+	var buf bytes.Buffer
+	if err := newMsgPackEncoder(&buf).Encode(v); err != nil {
+		Error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", MsgPack)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// newMsgPackEncoder returns a MessagePack encoder that names fields as
+// encoding/json does and writes integers in as few bytes as they fit.
+func newMsgPackEncoder(w io.Writer) *msgpack.Encoder {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	return enc
+}
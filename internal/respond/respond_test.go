@@ -0,0 +1,148 @@
+package respond
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shahinzaman102/Go_JumpStart/internal/data"
+	"github.com/shahinzaman102/Go_JumpStart/internal/problem"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", JSON},    // no preference: the first offer
+		{"*/*", JSON}, // curl
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", HTML}, // a browser
+		{"text/plain", Text},
+		{"application/problem+json", JSON},
+		{"text/*;q=0.5, application/json;q=0.4", HTML},
+		{"text/html;q=0, */*", JSON}, // q=0 rules HTML out
+		{"image/png", JSON},          // nothing acceptable: still the first offer
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := Negotiate(r, JSON, HTML, Text); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{data.ErrBookNotFound, http.StatusNotFound},
+		{fmt.Errorf("loading order: %w", data.ErrAlbumNotFound), http.StatusNotFound},
+		{data.ErrVersionConflict, http.StatusPreconditionFailed},
+		{data.ErrUsernameTaken, http.StatusConflict},
+		{data.ErrNotEnoughStock, http.StatusConflict},
+		{fmt.Errorf("listing albums: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{problem.New(http.StatusTeapot, "short and stout"), http.StatusTeapot},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := Status(tt.err); got != tt.want {
+			t.Errorf("Status(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	send := func(accept string, err error) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/books/9", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		Error(w, r, err)
+		return w
+	}
+
+	w := send("application/json", data.ErrBookNotFound)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != http.StatusNotFound || p.Detail != "Book not found." || p.Instance != "/books/9" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	w = send("text/html", data.ErrBookNotFound)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), HTML) || !strings.Contains(w.Body.String(), "<h1>404 Not Found</h1>") {
+		t.Errorf("expected an error page, got %q: %s", w.Header().Get("Content-Type"), w.Body)
+	}
+
+	w = send("text/plain", data.ErrBookNotFound)
+	if w.Body.String() != "404 Not Found: Book not found.\n" {
+		t.Errorf("unexpected text %q", w.Body)
+	}
+
+	// Unexpected errors are logged, not shown
+	w = send("application/json", errors.New("dial tcp 10.0.0.3:3306: connection refused"))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "3306") {
+		t.Errorf("internal error leaked: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestStream(t *testing.T) {
+	send := func(accept string, err error) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/admin/multi-query", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		s := NewStream(w, r)
+		s.Field("albums")
+		if err == nil {
+			s.Send(map[string]int{"id": 1})
+			s.Send(map[string]int{"id": 2})
+			s.Field("customers") // none
+		}
+		s.End(err)
+		return w
+	}
+
+	w := send(JSON, nil)
+	if got := w.Body.String(); got != "{\"albums\":[{\"id\":1},\n{\"id\":2}],\"customers\":[]}\n" {
+		t.Errorf("unexpected JSON %q", got)
+	}
+	w = send(NDJSON, nil)
+	if got := w.Body.String(); got != "{\"albums\":{\"id\":1}}\n{\"albums\":{\"id\":2}}\n" {
+		t.Errorf("unexpected NDJSON %q", got)
+	}
+	w = send(MsgPack, nil)
+	if w.Header().Get("Content-Type") != MsgPack {
+		t.Fatalf("expected MessagePack, got %q", w.Header().Get("Content-Type"))
+	}
+	dec := msgpack.NewDecoder(w.Body)
+	for id := 1; id <= 2; id++ {
+		var item map[string]map[string]int
+		if err := dec.Decode(&item); err != nil || item["albums"]["id"] != id {
+			t.Errorf("unexpected MessagePack item %v: %v", item, err)
+		}
+	}
+	if _, err := dec.DecodeInterface(); err != io.EOF {
+		t.Errorf("expected two items, then the end: %v", err)
+	}
+
+	// An error before the first item is still a proper response
+	w = send(JSON, data.ErrAlbumNotFound)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("expected a 404 problem, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
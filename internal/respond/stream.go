@@ -0,0 +1,221 @@
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// flushEvery is how many items a Stream writes between flushes, so clients
+// get rows as they are read rather than when the server's buffer fills.
+const flushEvery = 100
+
+// Stream sends a collection item by item as it is read, so a response takes
+// the same memory whatever the size of the collection: a JSON array, or one
+// JSON value per line for clients that accept NDJSON. MessagePack clients get
+// a sequence of values, one per item as in NDJSON, since a MessagePack array
+// starts with its length, which isn't known until the last item.
+//
+// Nothing is written before the first item, so an error that comes earlier
+// (a failed query, say) still gets a proper error response from End.
+type Stream struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	rc     *http.ResponseController
+	format string
+	item   bytes.Buffer     // the item being encoded
+	enc    *msgpack.Encoder // encodes MessagePack items into item
+
+	started   bool
+	field     string // the object field the items go to, if any
+	fieldOpen bool   // whether the field's name and [ have been written
+	fields    int    // fields written so far
+	n         int    // items in the current array
+	total     int    // items sent
+	err       error  // the first write error
+}
+
+// NewStream starts a streamed response to r in the format the client accepts.
+func NewStream(w http.ResponseWriter, r *http.Request) *Stream {
+	VaryAccept(w)
+	s := &Stream{
+		w:      w,
+		r:      r,
+		rc:     http.NewResponseController(w),
+		format: Negotiate(r, JSON, NDJSON, MsgPack),
+	}
+	if s.format == MsgPack {
+		s.enc = newMsgPackEncoder(&s.item)
+	}
+	return s
+}
+
+// Field makes the items sent after it an array field of an object wrapping
+// the response: {"albums":[...],"customers":[...]}. In NDJSON and MessagePack,
+// which have no enclosing object, each of them comes as {"customers":item}.
+// Calling it again with the current field does nothing.
+func (s *Stream) Field(name string) {
+	if s.field == name {
+		return
+	}
+	if s.format == JSON {
+		if s.field != "" {
+			s.openField() // an empty array still gets its field
+			s.write([]byte("]"))
+		}
+		s.fieldOpen = false
+	}
+	s.field, s.n = name, 0
+}
+
+// Send writes v as the next item. It returns the request context's error
+// once the client has gone, or the error writing to it, and the caller
+// should stop reading then.
+func (s *Stream) Send(v any) error {
+	if err := s.r.Context().Err(); err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.item.Reset()
+	if s.format == MsgPack {
+		if s.field != "" {
+			v = map[string]any{s.field: v}
+		}
+		if err := s.enc.Encode(v); err != nil {
+			return err
+		}
+		s.start()
+		s.write(s.item.Bytes())
+		return s.sent()
+	}
+	if err := json.NewEncoder(&s.item).Encode(v); err != nil {
+		return err
+	}
+	line := bytes.TrimSuffix(s.item.Bytes(), []byte("\n"))
+	if s.format == NDJSON {
+		s.start()
+		if s.field != "" {
+			s.write(fieldName(s.field))
+			s.write(line)
+			s.write([]byte("}\n"))
+		} else {
+			s.write(line)
+			s.write([]byte("\n"))
+		}
+	} else {
+		s.openField()
+		if s.n > 0 {
+			s.write([]byte(",\n"))
+		}
+		s.write(line)
+	}
+	return s.sent()
+}
+
+// sent counts the item just written, flushing every flushEvery items, and
+// returns the error writing it, if any.
+func (s *Stream) sent() error {
+	s.n++
+	s.total++
+	if s.total%flushEvery == 0 {
+		s.flush()
+	}
+	return s.err
+}
+
+// End finishes the response; err is what stopped the items, if anything.
+// Before the first item, an error is sent with Error. After it the status
+// has gone out: the error is logged and the connection aborted, so clients
+// see a broken response rather than a short one. Nothing is done once the
+// client has gone.
+func (s *Stream) End(err error) {
+	if err != nil {
+		if s.err != nil || s.r.Context().Err() != nil {
+			return // the client has gone
+		}
+		if !s.started {
+			Error(s.w, s.r, err)
+			return
+		}
+		log.Printf("%s %s: stream stopped after %d items: %v", s.r.Method, s.r.URL.Path, s.total, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if s.format == JSON {
+		s.openField()
+		s.write([]byte("]"))
+		if s.field != "" {
+			s.write([]byte("}"))
+		}
+		s.write([]byte("\n"))
+	} else {
+		s.start()
+	}
+	s.flush()
+}
+
+// start writes the headers, and for a JSON array the opening bracket or
+// brace, the first time it is called.
+func (s *Stream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", s.format)
+	s.w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.w.WriteHeader(http.StatusOK)
+	if s.format != JSON {
+		return
+	}
+	if s.field != "" {
+		s.write([]byte("{"))
+	} else {
+		s.write([]byte("["))
+	}
+}
+
+// openField writes the name and opening bracket of the current JSON field,
+// if it has one and they haven't been written yet.
+func (s *Stream) openField() {
+	s.start()
+	if s.field == "" || s.fieldOpen {
+		return
+	}
+	if s.fields > 0 {
+		s.write([]byte(","))
+	}
+	name, _ := json.Marshal(s.field)
+	s.write(name)
+	s.write([]byte(":["))
+	s.fieldOpen = true
+	s.fields++
+}
+
+// fieldName returns the start of an NDJSON line wrapping an item in field:
+// {"field":
+func fieldName(field string) []byte {
+	name, _ := json.Marshal(field)
+	return append(append([]byte("{"), name...), ':')
+}
+
+// write writes b unless an earlier write failed, keeping the first error.
+func (s *Stream) write(b []byte) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.Write(b)
+}
+
+// flush sends what has been written so far. Writers that can't flush
+// (http.ErrNotSupported) send it when the handler returns.
+func (s *Stream) flush() {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) && s.err == nil {
+		s.err = err
+	}
+}
@@ -3,8 +3,10 @@ package routes
 import (
 	"net/http"
 
+	"github.com/shahinzaman102/Go_JumpStart/internal/config"
 	"github.com/shahinzaman102/Go_JumpStart/internal/handlers"
 	"github.com/shahinzaman102/Go_JumpStart/internal/middleware"
+	"github.com/shahinzaman102/Go_JumpStart/internal/respond"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -24,15 +26,21 @@ func Register() http.Handler {
 	r.Use(middleware.Tracing)
 
 	// --- CORS ---
+	// The same origins may open WebSockets, which CORS doesn't cover
+	origins := config.AllowedOrigins()
+	handlers.SetWebSocketOrigins(origins)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"}, // front-end URLs
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins:   origins, // front-end URLs
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by browsers
 	}))
 
+	// Unknown paths get a 404 page, problem details or text, as the client accepts
+	r.NotFound(respond.NotFound)
+
 	// HTTP routers -->
 
 	// --- App Home ---
@@ -73,9 +81,22 @@ func Register() http.Handler {
 		r.Get("/total", handlers.GetTotalBookPrice)
 		r.Get("/{id}", handlers.GetBookByID)
 		r.Put("/{id}", handlers.UpdateBook)
+		r.Patch("/{id}", handlers.PatchBook)
 		r.Delete("/{id}", handlers.DeleteBook)
 	})
 
+	// --- Authors & Genres API ---
+	r.Route("/authors", func(r chi.Router) {
+		r.Get("/", handlers.GetAuthors)
+		r.Post("/", handlers.CreateAuthor)
+		r.Get("/{id}/books", handlers.GetBooksByAuthor)
+	})
+	r.Route("/genres", func(r chi.Router) {
+		r.Get("/", handlers.GetGenres)
+		r.Post("/", handlers.CreateGenre)
+		r.Get("/{id}/books", handlers.GetBooksByGenre)
+	})
+
 	// --- Albums API ---
 	r.Route("/albums", func(r chi.Router) {
 		r.Get("/", handlers.GetAllAlbums)
@@ -84,6 +105,13 @@ func Register() http.Handler {
 		r.Get("/timeout", handlers.QueryWithTimeout)
 		r.Get("/{id}/can-purchase", handlers.CanPurchaseAlbum)
 		r.Get("/{id}", handlers.GetAlbumByID)
+		r.Patch("/{id}", handlers.PatchAlbum)
+	})
+
+	// --- Artists API ---
+	r.Route("/artists", func(r chi.Router) {
+		r.Get("/", handlers.GetArtists)
+		r.Get("/{id}/albums", handlers.GetAlbumsByArtistID)
 	})
 
 	// --- Orders API ---
@@ -100,17 +128,35 @@ func Register() http.Handler {
 	r.Get("/view", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/view/FrontPage", http.StatusFound)
 	})
-	r.Get("/view/{title}", handlers.ViewWiki)
-	r.Get("/edit/{title}", handlers.EditWiki)
-	r.Post("/save/{title}", handlers.SaveWiki)
+	r.Get("/view/*", handlers.ViewWiki) // * = page title, may be namespaced (e.g. Guides/Setup)
+	r.Get("/edit/*", handlers.EditWiki)
+	r.Post("/save/*", handlers.SaveWiki)
+	r.Get("/history/*", handlers.HistoryWiki)
+	r.Get("/diff/*", handlers.DiffWiki)
+	r.Post("/revert/*", handlers.RevertWiki) // /revert/{title}/{rev}
+	r.Get("/wiki/index", handlers.WikiIndex) // all pages, orphans and wanted pages
+	r.Get("/attachments/*", handlers.ListAttachments)
+	r.Post("/attachments/*", handlers.UploadAttachment)
+	r.Get("/files/*", handlers.ServeAttachment)               // /files/{title}/{name}
+	r.Post("/delete-attachment/*", handlers.DeleteAttachment) // /delete-attachment/{title}/{name}
+
+	// --- Search ---
+	r.Get("/search", handlers.Search)
 
 	// --- JSON Utilities ---
 	r.Post("/json/encode", handlers.JsonEncode)
 	r.Post("/json/decode", handlers.JsonDecode)
 
 	// --- WebSocket ---
-	r.Get("/ws", handlers.Echo)                  // special endpoint → upgrades HTTP to a WebSocket connection.
+	r.Get("/ws", handlers.WebSocket)             // special endpoint → upgrades HTTP to a WebSocket connection.
+	r.Get("/ws/rooms", handlers.WebSocketRooms)  // lists the hub's rooms and members (login required).
+	r.Get("/ws/token", handlers.WebSocketToken)  // bearer token for clients without the session cookie.
+	r.Get("/ws/stats", handlers.WebSocketStats)  // connection and message counters (login required).
 	r.Get("/websockets", handlers.WebsocketPage) // opens the HTML page in browser.
+	r.Get("/events", handlers.EventStream)       // the hub's topic events as server-sent events, for clients that can't use /ws.
+
+	// --- Chat history (messages sent to WebSocket rooms) ---
+	r.Get("/rooms/{id}/messages", handlers.RoomMessages) // a page of a room's saved messages (?before={id}).
 
 	// --- Concurrency ---
 	r.Get("/concurrency/goroutines_waitgroup", handlers.GoroutinesWaitGroupHandler)
@@ -127,6 +173,13 @@ func Register() http.Handler {
 	r.Get("/go-basics", handlers.GoBasics)
 
 	r.Get("/pathfinder", handlers.Pathfinder)
+	r.Post("/pathfinder", handlers.SolvePathfinder)
+	r.Get("/pathfinder/generate", handlers.GeneratePathfinderGrid)
+	r.Post("/pathfinder/batch", handlers.BatchPathfinder)
+	r.Post("/pathfinder/nearest", handlers.NearestPathfinder)
+	r.Post("/pathfinder/agents", handlers.AgentsPathfinder)
+	r.Get("/pathfinder/stream", handlers.StreamPathfinder)         // server-sent search steps
+	r.Get("/pathfinder/playground", handlers.PathfinderPlayground) // opens the HTML page in browser.
 
 	r.Get("/runtime-errors", handlers.RuntimeErrorsHandler)
 
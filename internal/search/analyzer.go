@@ -0,0 +1,177 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common English words that carry no search value.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "to": true, "was": true, "with": true,
+}
+
+// token is a single analysed word plus its byte offsets in the original text.
+type token struct {
+	term       string // stemmed, lower-cased form used for lookups
+	start, end int    // byte range in the source text (used for snippets)
+}
+
+// tokenize splits text into lower-cased words, drops stop words and stems the rest.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendToken(tokens []token, text string, start, end int) []token {
+	word := strings.ToLower(text[start:end])
+	if stopWords[word] {
+		return tokens
+	}
+	return append(tokens, token{term: Stem(word), start: start, end: end})
+}
+
+// terms returns only the analysed terms of text (no offsets).
+func terms(text string) []string {
+	tokens := tokenize(text)
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = t.term
+	}
+	return out
+}
+
+// Stem reduces an English word to its stem using the core steps of the
+// Porter algorithm (plurals, -ed/-ing, -y and the most common derivational suffixes).
+// It is intentionally small: good enough for "trains" → "train" and "running" → "run".
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	w := []byte(word)
+
+	// Step 1a: plurals
+	switch {
+	case hasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case hasSuffix(w, "ies"):
+		w = w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+	case hasSuffix(w, "s"):
+		w = w[:len(w)-1]
+	}
+
+	// Step 1b: -eed, -ed, -ing
+	switch {
+	case hasSuffix(w, "eed"):
+		if measure(w[:len(w)-3]) > 0 {
+			w = w[:len(w)-1]
+		}
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		w = step1bFixup(w[:len(w)-2])
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		w = step1bFixup(w[:len(w)-3])
+	}
+
+	// Step 1c: y → i when there is another vowel
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w[len(w)-1] = 'i'
+	}
+
+	// Step 2/3 (subset): common derivational suffixes, repeated so "hopefulness" → "hopeful" → "hope"
+	for stripped := true; stripped; {
+		stripped = false
+		for _, rule := range derivationalSuffixes {
+			if hasSuffix(w, rule[0]) && measure(w[:len(w)-len(rule[0])]) > 0 {
+				w = append(w[:len(w)-len(rule[0])], rule[1]...)
+				stripped = true
+				break
+			}
+		}
+	}
+	return string(w)
+}
+
+// derivationalSuffixes maps a suffix to its replacement (Porter steps 2 and 3, abridged).
+var derivationalSuffixes = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"ization", "ize"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"alism", "al"}, {"ation", "ate"},
+	{"icate", "ic"}, {"alize", "al"}, {"ness", ""}, {"ful", ""},
+}
+
+// step1bFixup restores a trailing "e" or undoubles consonants after removing -ed/-ing.
+func step1bFixup(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "at"), hasSuffix(w, "bl"), hasSuffix(w, "iz"):
+		return append(w, 'e')
+	case len(w) >= 2 && w[len(w)-1] == w[len(w)-2] && isConsonant(w, len(w)-1) &&
+		!strings.ContainsRune("lsz", rune(w[len(w)-1])):
+		return w[:len(w)-1]
+	case measure(w) == 1 && endsCVC(w):
+		return append(w, 'e')
+	}
+	return w
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return len(w) > len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+// isConsonant reports whether w[i] is a consonant in the Porter sense ('y' after a consonant is a vowel).
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// measure counts vowel-consonant sequences ("m" in the Porter paper).
+func measure(w []byte) int {
+	m := 0
+	prevVowel := false
+	for i := range w {
+		vowel := !isConsonant(w, i)
+		if prevVowel && !vowel {
+			m++
+		}
+		prevVowel = vowel
+	}
+	return m
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant, where the last consonant isn't w, x or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	return !strings.ContainsRune("wxy", rune(w[n-1]))
+}
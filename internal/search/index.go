@@ -0,0 +1,256 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 tuning constants (the usual defaults).
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRadius is the number of bytes kept on each side of the first match.
+const snippetRadius = 80
+
+// Document is a searchable item: an album, a book or a wiki page.
+type Document struct {
+	Type  string // "album", "book" or "wiki"
+	ID    string // album/book ID or wiki page title
+	Title string
+	Body  string
+}
+
+// Result is a single ranked search hit.
+type Result struct {
+	Type    string  `json:"type"`
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"` // HTML-escaped text with matches wrapped in <mark>
+}
+
+// indexedDoc is a Document plus its analysed length.
+type indexedDoc struct {
+	Document
+	length int // number of terms (title + body)
+}
+
+// Index is an in-memory inverted index ranked with BM25. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*indexedDoc    // key → document
+	postings map[string]map[string]int // term → key → term frequency
+	totalLen int                       // sum of all document lengths (for avgdl)
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*indexedDoc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+func docKey(typ, id string) string {
+	return typ + ":" + id
+}
+
+// Add indexes a document, replacing any previous version with the same type and ID.
+func (idx *Index) Add(doc Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := docKey(doc.Type, doc.ID)
+	idx.remove(key)
+
+	docTerms := terms(doc.Title + " " + doc.Body)
+	for _, t := range docTerms {
+		if idx.postings[t] == nil {
+			idx.postings[t] = make(map[string]int)
+		}
+		idx.postings[t][key]++
+	}
+	idx.docs[key] = &indexedDoc{Document: doc, length: len(docTerms)}
+	idx.totalLen += len(docTerms)
+}
+
+// Remove deletes a document from the index (no-op if absent).
+func (idx *Index) Remove(typ, id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(docKey(typ, id))
+}
+
+// remove deletes a document by key. Caller must hold idx.mu.
+func (idx *Index) remove(key string) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for _, t := range terms(doc.Title + " " + doc.Body) {
+		delete(idx.postings[t], key)
+		if len(idx.postings[t]) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+	idx.totalLen -= doc.length
+	delete(idx.docs, key)
+}
+
+// Reset empties the index (used before a full rebuild).
+func (idx *Index) Reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs = make(map[string]*indexedDoc)
+	idx.postings = make(map[string]map[string]int)
+	idx.totalLen = 0
+}
+
+// Len returns the number of indexed documents.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search ranks documents matching any query term with BM25.
+// typ restricts results to one document type ("" = all). At most limit results are returned.
+func (idx *Index) Search(query, typ string, limit int) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryTerms := uniq(terms(query))
+	if len(queryTerms) == 0 || len(idx.docs) == 0 {
+		return []Result{}
+	}
+
+	n := float64(len(idx.docs))
+	avgLen := float64(idx.totalLen) / n
+	scores := make(map[string]float64)
+
+	for _, t := range queryTerms {
+		posting := idx.postings[t]
+		if len(posting) == 0 {
+			continue
+		}
+		// Okapi BM25 IDF (the +1 keeps it positive for very common terms)
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for key, tf := range posting {
+			doc := idx.docs[key]
+			if typ != "" && doc.Type != typ {
+				continue
+			}
+			f := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(doc.length)/avgLen
+			scores[key] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for key, score := range scores {
+		doc := idx.docs[key]
+		results = append(results, Result{
+			Type:    doc.Type,
+			ID:      doc.ID,
+			Title:   doc.Title,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: snippet(doc.Body, queryTerms),
+		})
+	}
+
+	// Highest score first; ties broken by key for stable output
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return docKey(results[i].Type, results[i].ID) < docKey(results[j].Type, results[j].ID)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// snippet returns a short, HTML-escaped excerpt of text around the first
+// matching term, with every matching word wrapped in <mark>...</mark>.
+func snippet(text string, queryTerms []string) string {
+	wanted := make(map[string]bool, len(queryTerms))
+	for _, t := range queryTerms {
+		wanted[t] = true
+	}
+
+	tokens := tokenize(text)
+	first := -1
+	for _, tok := range tokens {
+		if wanted[tok.term] {
+			first = tok.start
+			break
+		}
+	}
+
+	// Window around the first match (or the start of the text if only the title matched)
+	from := 0
+	if first >= 0 {
+		from = max(0, first-snippetRadius)
+	}
+	to := min(len(text), from+2*snippetRadius)
+	from, to = wordBoundary(text, from, true), wordBoundary(text, to, false)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, tok := range tokens {
+		if tok.start < from || tok.end > to || !wanted[tok.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:tok.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[tok.start:tok.end]))
+		b.WriteString("</mark>")
+		pos = tok.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// wordBoundary moves i to the nearest space so snippets don't cut words in half.
+// Scanning goes backwards when back is true, forwards otherwise.
+func wordBoundary(text string, i int, back bool) int {
+	if i <= 0 || i >= len(text) {
+		return i
+	}
+	if back {
+		if j := strings.LastIndexByte(text[:i], ' '); j >= 0 {
+			return j + 1
+		}
+		return 0
+	}
+	if j := strings.IndexByte(text[i:], ' '); j >= 0 {
+		return i + j
+	}
+	return len(text)
+}
+
+func uniq(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := items[:0]
+	for _, s := range items {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
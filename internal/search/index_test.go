@@ -0,0 +1,71 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"trains":      "train",
+		"running":     "run",
+		"hoping":      "hope",
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"agreed":      "agree",
+		"happy":       "happi",
+		"relational":  "relate",
+		"hopefulness": "hope",
+	}
+	for in, want := range tests {
+		if got := Stem(in); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchRanksAndFilters(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(Document{Type: "album", ID: "1", Title: "Blue Train", Body: "John Coltrane"})
+	idx.Add(Document{Type: "book", ID: "1", Title: "Blue Train", Body: "John Coltrane"})
+	idx.Add(Document{Type: "wiki", ID: "Trains", Title: "Trains", Body: "Trains and training: a train page about trains."})
+	idx.Add(Document{Type: "wiki", ID: "Other", Title: "Other", Body: "Nothing relevant here."})
+
+	got := idx.Search("train", "", 10)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 hits, got %d: %+v", len(got), got)
+	}
+	if got[0].ID != "Trains" {
+		t.Errorf("expected the wiki page with most matches first, got %+v", got[0])
+	}
+
+	got = idx.Search("train", "album", 10)
+	if len(got) != 1 || got[0].Type != "album" {
+		t.Errorf("expected only the album, got %+v", got)
+	}
+
+	// Re-adding replaces the old terms
+	idx.Add(Document{Type: "album", ID: "1", Title: "Giant Steps", Body: "John Coltrane"})
+	if got := idx.Search("train", "album", 10); len(got) != 0 {
+		t.Errorf("expected stale album terms to be removed, got %+v", got)
+	}
+
+	idx.Remove("wiki", "Trains")
+	if idx.Len() != 3 {
+		t.Errorf("expected 3 docs after remove, got %d", idx.Len())
+	}
+}
+
+func TestSnippetHighlightsAndEscapes(t *testing.T) {
+	got := snippet("Use <b>Go</b> for running servers", []string{Stem("run")})
+	want := "Use &lt;b&gt;Go&lt;/b&gt; for <mark>running</mark> servers"
+	if got != want {
+		t.Errorf("snippet = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("filler ", 50) + "needle" + strings.Repeat(" filler", 50)
+	got = snippet(long, []string{Stem("needle")})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "<mark>needle</mark>") {
+		t.Errorf("unexpected long snippet: %q", got)
+	}
+}
@@ -0,0 +1,223 @@
+package wiki
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxAttachmentSize is the largest file that can be attached to a page (5 MB).
+const MaxAttachmentSize = 5 << 20
+
+// attachmentDirExt is appended to a page's title to get the directory holding its
+// attachments ("Guides/Setup.files/diagram.png"). Titles can't contain '.', so
+// it never collides with a page or namespace.
+const attachmentDirExt = ".files"
+
+var (
+	// ErrInvalidAttachment is returned for bad file names and disallowed or mislabelled file types.
+	ErrInvalidAttachment = errors.New("invalid attachment name or type")
+	// ErrAttachmentTooLarge is returned for files over MaxAttachmentSize.
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	// ErrAttachmentNotFound is returned when a page has no attachment with the given name.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// attachmentType describes an allowed file extension. The uploaded content is
+// sniffed and must match (sniff is a prefix of http.DetectContentType's result).
+// SVG and HTML are deliberately not allowed, as browsers can run scripts in them.
+type attachmentType struct {
+	contentType string
+	sniff       string
+	image       bool
+}
+
+var attachmentTypes = map[string]attachmentType{
+	".png":  {"image/png", "image/png", true},
+	".jpg":  {"image/jpeg", "image/jpeg", true},
+	".jpeg": {"image/jpeg", "image/jpeg", true},
+	".gif":  {"image/gif", "image/gif", true},
+	".webp": {"image/webp", "image/webp", true},
+	".pdf":  {"application/pdf", "application/pdf", false},
+	".txt":  {"text/plain; charset=utf-8", "text/plain", false},
+	".csv":  {"text/csv; charset=utf-8", "text/plain", false},
+	".md":   {"text/markdown; charset=utf-8", "text/plain", false},
+}
+
+// Attachment describes a file attached to a page.
+type Attachment struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Image       bool      `json:"image"` // shown inline rather than downloaded
+	Modified    time.Time `json:"modified"`
+}
+
+// ValidateAttachmentName checks a file name: the part before the extension must
+// follow the same rules as a title segment, and the extension must be allowed.
+func ValidateAttachmentName(name string) error {
+	_, err := attachmentTypeOf(name)
+	return err
+}
+
+// attachmentTypeOf validates name and returns the type for its extension.
+func attachmentTypeOf(name string) (attachmentType, error) {
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return attachmentType{}, ErrInvalidAttachment
+	}
+	if ValidateTitle(name[:dot]) != nil || strings.Contains(name[:dot], "/") {
+		return attachmentType{}, ErrInvalidAttachment
+	}
+	typ, ok := attachmentTypes[strings.ToLower(name[dot:])]
+	if !ok {
+		return attachmentType{}, ErrInvalidAttachment
+	}
+	return typ, nil
+}
+
+// validateUpload checks a new attachment's title, name, size and content
+// and returns its type. Shared by all Store implementations.
+func validateUpload(title, name string, content []byte) (attachmentType, error) {
+	typ, err := checkAttachment(title, name)
+	if err != nil {
+		return attachmentType{}, err
+	}
+	if len(content) > MaxAttachmentSize {
+		return attachmentType{}, ErrAttachmentTooLarge
+	}
+	if !strings.HasPrefix(http.DetectContentType(content), typ.sniff) {
+		return attachmentType{}, ErrInvalidAttachment
+	}
+	return typ, nil
+}
+
+// SaveAttachment stores a file beside an existing page, replacing any file with the same name.
+func (s *FileStore) SaveAttachment(title, name string, content []byte) (Attachment, error) {
+	typ, err := validateUpload(title, name, content)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if _, err := s.Load(title); err != nil {
+		return Attachment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeFile(attachmentPath(title, name), content); err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{
+		Name:        name,
+		Size:        int64(len(content)),
+		ContentType: typ.contentType,
+		Image:       typ.image,
+		Modified:    time.Now().UTC(),
+	}, nil
+}
+
+// Attachments lists a page's attachments, sorted by name (empty if none).
+func (s *FileStore) Attachments(title string) ([]Attachment, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(s.root.FS(), title+attachmentDirExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Attachment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []Attachment{}
+	for _, e := range entries {
+		typ, err := attachmentTypeOf(e.Name())
+		if err != nil || !e.Type().IsRegular() { // skip anything we wouldn't have written
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, Attachment{
+			Name:        e.Name(),
+			Size:        info.Size(),
+			ContentType: typ.contentType,
+			Image:       typ.image,
+			Modified:    info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	return attachments, nil
+}
+
+// Attachment returns the content and details of one attachment.
+func (s *FileStore) Attachment(title, name string) ([]byte, Attachment, error) {
+	typ, err := checkAttachment(title, name)
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	p := attachmentPath(title, name)
+	info, err := s.root.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Attachment{}, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	content, err := s.readFile(p)
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	return content, Attachment{
+		Name:        name,
+		Size:        info.Size(),
+		ContentType: typ.contentType,
+		Image:       typ.image,
+		Modified:    info.ModTime().UTC(),
+	}, nil
+}
+
+// DeleteAttachment removes an attachment.
+func (s *FileStore) DeleteAttachment(title, name string) error {
+	if _, err := checkAttachment(title, name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.root.Remove(attachmentPath(title, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrAttachmentNotFound
+	}
+	return err
+}
+
+// checkAttachment validates a page title and attachment name and returns the file's type.
+func checkAttachment(title, name string) (attachmentType, error) {
+	if err := ValidateTitle(title); err != nil {
+		return attachmentType{}, err
+	}
+	return attachmentTypeOf(name)
+}
+
+// AttachmentURL is the URL an attachment is served from ("/files/<title>/<name>").
+func AttachmentURL(title, name string) string {
+	return PageURL("files", title) + "/" + url.PathEscape(name)
+}
+
+// attachmentPath is the path of an attachment relative to the store root.
+func attachmentPath(title, name string) string {
+	return path.Join(title+attachmentDirExt, name)
+}
+
+// isAttachmentDir reports whether a store-relative directory holds attachments.
+func isAttachmentDir(p string) bool {
+	return strings.HasSuffix(p, attachmentDirExt)
+}
@@ -0,0 +1,80 @@
+package wiki
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestValidateAttachmentName(t *testing.T) {
+	valid := []string{"diagram.png", "Photo 1.JPG", "notes.pdf", "data-2024.csv"}
+	invalid := []string{
+		"", "noext", ".png", "../x.png", "a/b.png", "x.svg", "x.html", "x.png.html",
+		"x.tar.gz", "con.png", "trailing .png",
+	}
+	for _, name := range valid {
+		if err := ValidateAttachmentName(name); err != nil {
+			t.Errorf("ValidateAttachmentName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range invalid {
+		if err := ValidateAttachmentName(name); err == nil {
+			t.Errorf("ValidateAttachmentName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestFileStoreAttachments(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	if _, err := s.SaveAttachment("Guides/Setup", "diagram.png", pngHeader); err != ErrPageNotFound {
+		t.Errorf("expected ErrPageNotFound for a missing page, got %v", err)
+	}
+	if _, err := s.Save("Guides/Setup", Revision{Body: "![[diagram.png]]"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	att, err := s.SaveAttachment("Guides/Setup", "diagram.png", pngHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if att.ContentType != "image/png" || !att.Image {
+		t.Errorf("unexpected attachment details: %+v", att)
+	}
+
+	// Content must match the extension, and size is limited
+	if _, err := s.SaveAttachment("Guides/Setup", "fake.png", []byte("<script>alert(1)</script>")); err != ErrInvalidAttachment {
+		t.Errorf("expected ErrInvalidAttachment for mislabelled content, got %v", err)
+	}
+	big := append(bytes.Clone(pngHeader), make([]byte, MaxAttachmentSize)...)
+	if _, err := s.SaveAttachment("Guides/Setup", "big.png", big); err != ErrAttachmentTooLarge {
+		t.Errorf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+
+	list, err := s.Attachments("Guides/Setup")
+	if err != nil || len(list) != 1 || list[0].Name != "diagram.png" {
+		t.Errorf("unexpected attachment list: %+v, %v", list, err)
+	}
+	content, _, err := s.Attachment("Guides/Setup", "diagram.png")
+	if err != nil || !bytes.Equal(content, pngHeader) {
+		t.Errorf("unexpected attachment content: %q, %v", content, err)
+	}
+
+	// Attachment directories aren't pages
+	titles, _ := s.List()
+	if len(titles) != 1 || titles[0] != "Guides/Setup" {
+		t.Errorf("unexpected page list: %v", titles)
+	}
+
+	if err := s.DeleteAttachment("Guides/Setup", "diagram.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := s.Attachment("Guides/Setup", "diagram.png"); err != ErrAttachmentNotFound {
+		t.Errorf("expected ErrAttachmentNotFound after delete, got %v", err)
+	}
+	if err := s.DeleteAttachment("Guides/Setup", "diagram.png"); err != ErrAttachmentNotFound {
+		t.Errorf("expected ErrAttachmentNotFound deleting twice, got %v", err)
+	}
+}
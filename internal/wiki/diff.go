@@ -0,0 +1,161 @@
+package wiki
+
+import "strings"
+
+// DiffOp is the kind of change a DiffLine represents.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is one line of a line-based diff.
+// OldLine/NewLine are 1-based line numbers (0 when the line doesn't exist on that side).
+type DiffLine struct {
+	Op      DiffOp
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// splitLines splits text into lines, normalising Windows line endings.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// maxDiffWork caps the line comparisons of a Diff (the product of the line
+// counts left once the common start and end are trimmed). Past it the changed
+// middle is shown as deleted then inserted as a whole, so that diffing two
+// huge, unrelated texts can't tie up the server.
+const maxDiffWork = 50_000_000
+
+// Diff compares two texts line by line and returns the lines in order, marked
+// as equal, inserted or deleted. It finds a longest common subsequence with
+// Hirschberg's algorithm, in memory linear in the number of lines, and puts
+// deletions before insertions (like diff -u).
+func Diff(oldText, newText string) []DiffLine {
+	d := differ{a: splitLines(oldText), b: splitLines(newText)}
+	a, b := d.a, d.b
+
+	// Lines at the start and end that didn't change need no comparing
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	d.equal(0, 0, pre)
+	if aMid, bMid := len(a)-pre-suf, len(b)-pre-suf; aMid*bMid > maxDiffWork {
+		d.replace(pre, len(a)-suf, pre, len(b)-suf)
+	} else {
+		d.diff(pre, len(a)-suf, pre, len(b)-suf)
+	}
+	d.equal(len(a)-suf, len(b)-suf, suf)
+	return d.out
+}
+
+// differ accumulates the diff of a against b.
+type differ struct {
+	a, b []string
+	out  []DiffLine
+}
+
+// diff appends the diff of a[a0:a1] against b[b0:b1]: it splits a in half,
+// finds where in b that split falls on a longest common subsequence, and
+// diffs both halves.
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.equal(a0, b0, 1)
+		a0++
+		b0++
+	}
+	switch {
+	case a0 == a1 || b0 == b1:
+		d.replace(a0, a1, b0, b1)
+		return
+	case a1-a0 == 1:
+		for j := b0; j < b1; j++ {
+			if d.a[a0] == d.b[j] {
+				d.replace(a0, a0, b0, j)
+				d.equal(a0, j, 1)
+				d.replace(a1, a1, j+1, b1)
+				return
+			}
+		}
+		d.replace(a0, a1, b0, b1)
+		return
+	}
+
+	mid := (a0 + a1) / 2
+	front := d.lcsFront(a0, mid, b0, b1)
+	back := d.lcsBack(mid, a1, b0, b1)
+	split, best := b0, -1
+	for k := 0; k <= b1-b0; k++ {
+		// The first best split gives the first half the fewest lines of b,
+		// which puts deletions first
+		if n := front[k] + back[k]; n > best {
+			split, best = b0+k, n
+		}
+	}
+	d.diff(a0, mid, b0, split)
+	d.diff(mid, a1, split, b1)
+}
+
+// lcsFront returns, for each k, the LCS length of a[a0:a1] and b[b0:b0+k].
+func (d *differ) lcsFront(a0, a1, b0, b1 int) []int {
+	prev, curr := make([]int, b1-b0+1), make([]int, b1-b0+1)
+	for i := a0; i < a1; i++ {
+		for k := 1; k <= b1-b0; k++ {
+			if d.a[i] == d.b[b0+k-1] {
+				curr[k] = prev[k-1] + 1
+			} else {
+				curr[k] = max(prev[k], curr[k-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// lcsBack returns, for each k, the LCS length of a[a0:a1] and b[b0+k:b1].
+func (d *differ) lcsBack(a0, a1, b0, b1 int) []int {
+	n := b1 - b0
+	prev, curr := make([]int, n+1), make([]int, n+1)
+	for i := a1 - 1; i >= a0; i-- {
+		for k := n - 1; k >= 0; k-- {
+			if d.a[i] == d.b[b0+k] {
+				curr[k] = prev[k+1] + 1
+			} else {
+				curr[k] = max(prev[k], curr[k+1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// equal appends n unchanged lines starting at a[i] and b[j].
+func (d *differ) equal(i, j, n int) {
+	for k := 0; k < n; k++ {
+		d.out = append(d.out, DiffLine{Op: DiffEqual, Text: d.a[i+k], OldLine: i + k + 1, NewLine: j + k + 1})
+	}
+}
+
+// replace appends a[a0:a1] as deleted, then b[b0:b1] as inserted.
+func (d *differ) replace(a0, a1, b0, b1 int) {
+	for i := a0; i < a1; i++ {
+		d.out = append(d.out, DiffLine{Op: DiffDelete, Text: d.a[i], OldLine: i + 1})
+	}
+	for j := b0; j < b1; j++ {
+		d.out = append(d.out, DiffLine{Op: DiffInsert, Text: d.b[j], NewLine: j + 1})
+	}
+}
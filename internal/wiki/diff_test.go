@@ -0,0 +1,88 @@
+package wiki
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\n", "a\nx\nc\nd")
+
+	want := []DiffLine{
+		{Op: DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+		{Op: DiffDelete, Text: "b", OldLine: 2},
+		{Op: DiffInsert, Text: "x", NewLine: 2},
+		{Op: DiffEqual, Text: "c", OldLine: 3, NewLine: 3},
+		{Op: DiffInsert, Text: "d", NewLine: 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffNormalisesCRLF(t *testing.T) {
+	for _, l := range Diff("a\r\nb", "a\nb") {
+		if l.Op != DiffEqual {
+			t.Errorf("expected only equal lines, got %+v", l)
+		}
+	}
+}
+
+func TestDiffIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+	for n := 0; n < 500; n++ {
+		oldText, newText := randomText(), randomText()
+		a, b := splitLines(oldText), splitLines(newText)
+
+		var oldLines, newLines []string
+		equal := 0
+		for _, l := range Diff(oldText, newText) {
+			if l.Op != DiffInsert {
+				oldLines = append(oldLines, l.Text)
+			}
+			if l.Op != DiffDelete {
+				newLines = append(newLines, l.Text)
+			}
+			if l.Op == DiffEqual {
+				equal++
+			}
+		}
+		if strings.Join(oldLines, "\n") != strings.Join(a, "\n") || strings.Join(newLines, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diff of %q and %q doesn't rebuild them", oldText, newText)
+		}
+		if want := lcsLength(a, b); equal != want {
+			t.Fatalf("diff of %q and %q keeps %d lines, want %d", oldText, newText, equal, want)
+		}
+	}
+}
+
+// lcsLength is the textbook quadratic LCS, to check Diff against.
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				lcs[i][j] = lcs[i-1][j-1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i-1][j], lcs[i][j-1])
+			}
+		}
+	}
+	return lcs[len(a)][len(b)]
+}
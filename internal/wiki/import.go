@@ -0,0 +1,122 @@
+package wiki
+
+// ImportResult summarises an Import run.
+type ImportResult struct {
+	Pages       int // pages copied or completed
+	Revisions   int
+	Attachments int
+	Skipped     []string // pages whose history in the destination differs from the source
+}
+
+// Import copies every page from src to dst with its revision history and
+// attachments, keeping titles, authors and timestamps.
+//
+// A page already in dst is compared with src revision by revision: if its
+// history is the start of the source's (an import that was interrupted) the
+// missing revisions and attachments are added, so an interrupted import can
+// be re-run; if it differs (the page was edited in dst) the page is left
+// alone and reported in Skipped.
+func Import(dst, src Store) (ImportResult, error) {
+	var result ImportResult
+
+	titles, err := src.List()
+	if err != nil {
+		return result, err
+	}
+	for _, title := range titles {
+		body, err := src.Load(title)
+		if err != nil {
+			return result, err
+		}
+		revs, err := src.Revisions(title)
+		if err != nil {
+			return result, err
+		}
+		// The page may have been changed outside the wiki since its last revision
+		if len(revs) == 0 || revs[len(revs)-1].Body != string(body) {
+			revs = append(revs, Revision{Author: "unknown", Summary: "Imported existing page", Body: string(body)})
+		}
+
+		have, err := dst.Revisions(title)
+		if err != nil {
+			return result, err
+		}
+		if len(have) == 0 {
+			// A page without history in dst can't be compared
+			if _, err := dst.Load(title); err == nil {
+				result.Skipped = append(result.Skipped, title)
+				continue
+			} else if err != ErrPageNotFound {
+				return result, err
+			}
+		}
+		if !sameHistory(have, revs) {
+			result.Skipped = append(result.Skipped, title)
+			continue
+		}
+		for _, rev := range revs[len(have):] {
+			if _, err := dst.Save(title, rev); err != nil {
+				return result, err
+			}
+			result.Revisions++
+		}
+
+		copied, err := importAttachments(dst, src, title)
+		if err != nil {
+			return result, err
+		}
+		result.Attachments += copied
+		if len(have) < len(revs) || copied > 0 {
+			result.Pages++
+		}
+	}
+	return result, nil
+}
+
+// sameHistory reports whether have, the revisions of a page in the
+// destination, are the first revisions of want. Timestamps aren't compared:
+// an imported "existing page" revision gets the time it was imported.
+func sameHistory(have, want []Revision) bool {
+	if len(have) > len(want) {
+		return false
+	}
+	for i, rev := range have {
+		if rev.Body != want[i].Body || rev.Author != want[i].Author || rev.Summary != want[i].Summary {
+			return false
+		}
+	}
+	return true
+}
+
+// importAttachments copies the attachments of a page that dst doesn't have
+// yet and returns how many it copied.
+func importAttachments(dst, src Store, title string) (int, error) {
+	existing, err := dst.Attachments(title)
+	if err != nil {
+		return 0, err
+	}
+	have := map[string]bool{}
+	for _, att := range existing {
+		have[att.Name] = true
+	}
+
+	attachments, err := src.Attachments(title)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, att := range attachments {
+		if have[att.Name] {
+			continue
+		}
+		content, _, err := src.Attachment(title, att.Name)
+		if err != nil {
+			return copied, err
+		}
+		if _, err := dst.SaveAttachment(title, att.Name, content); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
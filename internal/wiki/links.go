@@ -0,0 +1,144 @@
+package wiki
+
+import (
+	"sort"
+	"sync"
+)
+
+// Links returns the distinct, sorted titles a page body links to,
+// using the same rules as Render (code blocks and invalid titles are ignored).
+func Links(body []byte) []string {
+	seen := map[string]bool{}
+	replaceLinks("", string(body), func(title string) string {
+		seen[title] = true
+		return ""
+	}, func(string, string) string { return "" })
+	return sortedKeys(seen)
+}
+
+// WantedPage is a page that is linked to but doesn't exist yet.
+type WantedPage struct {
+	Title      string   `json:"title"`
+	LinkedFrom []string `json:"linked_from"`
+}
+
+// LinkGraph tracks which pages link to which. It is safe for concurrent use.
+type LinkGraph struct {
+	mu  sync.RWMutex
+	out map[string][]string        // page -> pages it links to; every saved page has an entry
+	in  map[string]map[string]bool // page -> pages linking to it
+}
+
+// NewLinkGraph returns an empty link graph.
+func NewLinkGraph() *LinkGraph {
+	return &LinkGraph{
+		out: map[string][]string{},
+		in:  map[string]map[string]bool{},
+	}
+}
+
+// Update records the current body of a page, replacing its previous outgoing links.
+func (g *LinkGraph) Update(title string, body []byte) {
+	links := Links(body)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, target := range g.out[title] {
+		delete(g.in[target], title)
+		if len(g.in[target]) == 0 {
+			delete(g.in, target)
+		}
+	}
+	g.out[title] = links
+	for _, target := range links {
+		if g.in[target] == nil {
+			g.in[target] = map[string]bool{}
+		}
+		g.in[target][title] = true
+	}
+}
+
+// Pages returns every page in the graph, sorted.
+func (g *LinkGraph) Pages() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	pages := make([]string, 0, len(g.out))
+	for title := range g.out {
+		pages = append(pages, title)
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// Exists reports whether title is a page in the graph.
+func (g *LinkGraph) Exists(title string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	_, exists := g.out[title]
+	return exists
+}
+
+// Backlinks returns the pages that link to title, sorted (self-links excluded).
+func (g *LinkGraph) Backlinks(title string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	from := make(map[string]bool, len(g.in[title]))
+	for source := range g.in[title] {
+		if source != title {
+			from[source] = true
+		}
+	}
+	return sortedKeys(from)
+}
+
+// Orphans returns the existing pages no other page links to, sorted.
+func (g *LinkGraph) Orphans() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	orphans := []string{}
+	for title := range g.out {
+		linked := false
+		for source := range g.in[title] {
+			if source != title {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			orphans = append(orphans, title)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// Wanted returns the pages that are linked to but don't exist, sorted by title.
+func (g *LinkGraph) Wanted() []WantedPage {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	wanted := []WantedPage{}
+	for target, sources := range g.in {
+		if _, exists := g.out[target]; exists {
+			continue
+		}
+		wanted = append(wanted, WantedPage{Title: target, LinkedFrom: sortedKeys(sources)})
+	}
+	sort.Slice(wanted, func(i, j int) bool { return wanted[i].Title < wanted[j].Title })
+	return wanted
+}
+
+// sortedKeys returns the keys of a set in sorted order (never nil).
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
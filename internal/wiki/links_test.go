@@ -0,0 +1,57 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	body := "See [[Guides/Setup]] and [Page1], [Page1] again.\n" +
+		"- [x] done\n" +
+		"`[NotALink]`\n" +
+		"```\n[AlsoNotALink]\n```\n" +
+		"[[../bad]] [real](https://go.dev)"
+
+	got := Links([]byte(body))
+	want := []string{"Guides/Setup", "Page1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Links() = %v, want %v", got, want)
+	}
+}
+
+func TestLinkGraph(t *testing.T) {
+	g := NewLinkGraph()
+	g.Update("FrontPage", []byte("[[Page1]] and [[Missing]]"))
+	g.Update("Page1", []byte("Back to [[FrontPage]], self [[Page1]]"))
+	g.Update("Lonely", []byte("[[Missing]] [[Page1]]"))
+
+	if got := g.Backlinks("Page1"); !reflect.DeepEqual(got, []string{"FrontPage", "Lonely"}) {
+		t.Errorf("Backlinks(Page1) = %v", got)
+	}
+	if got := g.Orphans(); !reflect.DeepEqual(got, []string{"Lonely"}) {
+		t.Errorf("Orphans() = %v", got)
+	}
+	if !g.Exists("Page1") || g.Exists("Missing") {
+		t.Errorf("expected Page1 to exist and Missing not to")
+	}
+	want := []WantedPage{{Title: "Missing", LinkedFrom: []string{"FrontPage", "Lonely"}}}
+	if got := g.Wanted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted() = %+v, want %+v", got, want)
+	}
+
+	// Editing a page drops its old links and creating a wanted page removes it from the list
+	g.Update("Lonely", []byte("no links"))
+	g.Update("Missing", []byte("now exists"))
+	if got := g.Backlinks("Page1"); !reflect.DeepEqual(got, []string{"FrontPage"}) {
+		t.Errorf("Backlinks(Page1) after edit = %v", got)
+	}
+	if got := g.Wanted(); len(got) != 0 {
+		t.Errorf("expected no wanted pages, got %+v", got)
+	}
+	if !g.Exists("Missing") {
+		t.Error("expected a created page to exist")
+	}
+	if got := g.Orphans(); !reflect.DeepEqual(got, []string{"Lonely"}) {
+		t.Errorf("Orphans() after edit = %v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package wiki
+
+import (
+	"sync"
+	"time"
+)
+
+// Lock is an advisory edit lock: it tells other editors someone is working on
+// a page, but doesn't stop them from saving (conflicts are caught by Merge3).
+type Lock struct {
+	Title   string
+	Holder  string
+	Expires time.Time
+}
+
+// LockTable holds the current edit locks. It is safe for concurrent use.
+type LockTable struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	locks map[string]Lock
+	now   func() time.Time // replaceable in tests
+}
+
+// NewLockTable returns a lock table whose locks expire after ttl.
+func NewLockTable(ttl time.Duration) *LockTable {
+	return &LockTable{ttl: ttl, locks: map[string]Lock{}, now: time.Now}
+}
+
+// Acquire locks title for holder, or renews holder's existing lock.
+// If someone else holds an unexpired lock, it returns their lock and false.
+func (t *LockTable) Acquire(title, holder string) (Lock, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if l, ok := t.locks[title]; ok && l.Holder != holder && now.Before(l.Expires) {
+		return l, false
+	}
+	l := Lock{Title: title, Holder: holder, Expires: now.Add(t.ttl)}
+	t.locks[title] = l
+	return l, true
+}
+
+// Get returns the unexpired lock on title, if any.
+func (t *LockTable) Get(title string) (Lock, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.locks[title]
+	if !ok {
+		return Lock{}, false
+	}
+	if !t.now().Before(l.Expires) {
+		delete(t.locks, title)
+		return Lock{}, false
+	}
+	return l, true
+}
+
+// Release removes holder's lock on title. Locks held by others are left alone.
+func (t *LockTable) Release(title, holder string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l, ok := t.locks[title]; ok && l.Holder == holder {
+		delete(t.locks, title)
+	}
+}
@@ -0,0 +1,37 @@
+package wiki
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockTable(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	locks := NewLockTable(10 * time.Minute)
+	locks.now = func() time.Time { return now }
+
+	if _, ok := locks.Acquire("Page", "alice"); !ok {
+		t.Fatal("expected alice to get the lock")
+	}
+	if l, ok := locks.Acquire("Page", "bob"); ok || l.Holder != "alice" {
+		t.Errorf("expected bob to be refused while alice holds the lock, got %+v, %v", l, ok)
+	}
+
+	// Expired locks are free again
+	now = now.Add(11 * time.Minute)
+	if _, ok := locks.Get("Page"); ok {
+		t.Error("expected lock to have expired")
+	}
+	if _, ok := locks.Acquire("Page", "bob"); !ok {
+		t.Error("expected bob to get the expired lock")
+	}
+
+	locks.Release("Page", "alice") // not alice's any more
+	if l, ok := locks.Get("Page"); !ok || l.Holder != "bob" {
+		t.Errorf("expected bob's lock to survive alice's release, got %+v, %v", l, ok)
+	}
+	locks.Release("Page", "bob")
+	if _, ok := locks.Get("Page"); ok {
+		t.Error("expected lock to be released")
+	}
+}
@@ -0,0 +1,161 @@
+package wiki
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// wikiLink matches [[PageName]] links, and legacyLink the original [PageName]
+// syntax (as long as it isn't a Markdown link, i.e. not followed by "(", "[" or ":").
+// embedLink matches ![[file.png]] (or ![[Other/Page/file.png]]) attachment embeds.
+var (
+	embedLink  = regexp.MustCompile(`!\[\[([^\[\]\n]+)\]\]`)
+	wikiLink   = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+	legacyLink = regexp.MustCompile(`\[([^\[\]\n]+)\]([^(\[:]|$)`)
+	inlineCode = regexp.MustCompile("`[^`\n]*`")
+)
+
+// markdown converts CommonMark + GitHub tables/strikethrough/autolinks to HTML.
+// Raw HTML is passed through here and removed afterwards by the sanitiser.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+)
+
+// sanitizer is the allow-list applied to rendered pages: bluemonday's
+// user-generated-content policy plus the classes the wiki itself emits.
+var sanitizer = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^wikilink( wikilink-missing)?$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^wikiembed$`)).OnElements("img")
+	return p
+}()
+
+// PageURL builds "/<action>/<title>", escaping each namespace segment.
+func PageURL(action, title string) string {
+	segments := strings.Split(title, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg) // this encodes "page 1" to "page%201"
+	}
+	return "/" + action + "/" + strings.Join(segments, "/")
+}
+
+// Render converts the body of page title from Markdown to sanitised HTML.
+// [[Page]] (and legacy [Page]) become wiki links; links to pages for which
+// exists returns false get the "wikilink-missing" (red link) class.
+// ![[file.png]] embeds an image attached to the page.
+func Render(title string, body []byte, exists func(title string) bool) (string, error) {
+	source := expandWikiLinks(title, string(body), exists)
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return sanitizer.Sanitize(buf.String()), nil
+}
+
+// expandWikiLinks replaces wiki link and embed syntax with HTML.
+func expandWikiLinks(title, text string, exists func(string) bool) string {
+	return replaceLinks(title, text, func(target string) string {
+		return linkHTML(target, exists)
+	}, embedHTML)
+}
+
+// replaceLinks calls link for every [[Page]] and legacy [Page] link with a valid
+// title, and embed for every ![[file]] with a valid attachment name, substituting
+// their results. Embeds without a page prefix refer to the attachments of title.
+// Fenced code blocks, inline code, task list checkboxes and invalid names are left untouched.
+func replaceLinks(title, text string, link func(target string) string, embed func(page, name string) string) string {
+	lines := strings.Split(text, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		lines[i] = outsideInlineCode(line, func(s string) string {
+			s = embedLink.ReplaceAllStringFunc(s, func(m string) string {
+				page, name := splitEmbed(title, strings.TrimSpace(embedLink.FindStringSubmatch(m)[1]))
+				if ValidateTitle(page) != nil || ValidateAttachmentName(name) != nil {
+					return m
+				}
+				return embed(page, name)
+			})
+			s = wikiLink.ReplaceAllStringFunc(s, func(m string) string {
+				target := strings.TrimSpace(wikiLink.FindStringSubmatch(m)[1])
+				if ValidateTitle(target) != nil {
+					return m
+				}
+				return link(target)
+			})
+			return legacyLink.ReplaceAllStringFunc(s, func(m string) string {
+				sub := legacyLink.FindStringSubmatch(m)
+				target := strings.TrimSpace(sub[1])
+				if isTaskMarker(sub[1]) || ValidateTitle(target) != nil {
+					return m
+				}
+				return link(target) + sub[2]
+			})
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// outsideInlineCode applies fn to the parts of line that aren't `code spans`.
+func outsideInlineCode(line string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range inlineCode.FindAllStringIndex(line, -1) {
+		b.WriteString(fn(line[last:loc[0]]))
+		b.WriteString(line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(fn(line[last:]))
+	return b.String()
+}
+
+// linkHTML renders a single wiki link to a valid title.
+func linkHTML(title string, exists func(string) bool) string {
+	if !exists(title) {
+		return fmt.Sprintf(`<a class="wikilink wikilink-missing" href="%s">%s</a>`,
+			PageURL("edit", title), html.EscapeString(title))
+	}
+	return fmt.Sprintf(`<a class="wikilink" href="%s">%s</a>`,
+		PageURL("view", title), html.EscapeString(title))
+}
+
+// splitEmbed splits "Other/Page/file.png" into page and file name; a bare
+// file name refers to the current page.
+func splitEmbed(title, target string) (string, string) {
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return title, target
+}
+
+// embedHTML renders an attachment embed: images inline, other files as a download link.
+func embedHTML(page, name string) string {
+	src := AttachmentURL(page, name)
+	if typ, _ := attachmentTypeOf(name); typ.image {
+		return fmt.Sprintf(`<img class="wikiembed" src="%s" alt="%s">`, src, html.EscapeString(name))
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, src, html.EscapeString(name))
+}
+
+// isTaskMarker reports whether a legacy [x] match is really a GFM task list checkbox.
+func isTaskMarker(s string) bool {
+	return s == " " || s == "x" || s == "X"
+}
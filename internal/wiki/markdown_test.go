@@ -0,0 +1,82 @@
+package wiki
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	exists := func(title string) bool { return title == "FrontPage" || title == "Guides/Setup" }
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "markdown basics",
+			body: "# Title\n\n- one\n- two\n\n```go\nfmt.Println(\"[NotALink]\")\n```\n",
+			want: []string{"<h1", "<li>one</li>", `<code class="language-go">`, "[NotALink]"},
+		},
+		{
+			name: "table",
+			body: "| a | b |\n|---|---|\n| 1 | 2 |\n",
+			want: []string{"<table>", "<td>1</td>"},
+		},
+		{
+			name: "wiki links and red links",
+			body: "See [[FrontPage]], [[Guides/Setup]] and [[Missing Page]].",
+			want: []string{
+				`<a class="wikilink" href="/view/FrontPage"`,
+				`<a class="wikilink" href="/view/Guides/Setup"`,
+				`<a class="wikilink wikilink-missing" href="/edit/Missing%20Page"`,
+			},
+		},
+		{
+			name:    "legacy single-bracket links",
+			body:    "Check out [Page1] to get started. A [real link](https://go.dev) and `[code]` stay.",
+			want:    []string{`href="/edit/Page1"`, `<a href="https://go.dev"`, "<code>[code]</code>"},
+			notWant: []string{`href="/edit/code"`},
+		},
+		{
+			name:    "stored XSS is stripped",
+			body:    `<script>alert(1)</script><img src=x onerror="alert(2)"><a href="javascript:alert(3)">x</a>`,
+			notWant: []string{"<script", "onerror", "javascript:"},
+		},
+		{
+			name: "attachment embeds",
+			body: "![[diagram.png]] ![[Guides/Setup/notes.pdf]] ![[evil.svg]]",
+			want: []string{
+				`<img class="wikiembed" src="/files/FrontPage/diagram.png" alt="diagram.png"`,
+				`<a href="/files/Guides/Setup/notes.pdf"`,
+				"![[evil.svg]]",
+			},
+		},
+		{
+			name:    "invalid link titles stay text",
+			body:    "[[../etc/passwd]]",
+			want:    []string{"[[../etc/passwd]]"},
+			notWant: []string{"<a "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("FrontPage", []byte(tt.body), exists)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("expected %q in output:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("did not expect %q in output:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package wiki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Conflict markers written into merged text where both sides changed the same lines.
+const (
+	conflictStart = "<<<<<<< your changes"
+	conflictSep   = "======="
+	conflictEnd   = ">>>>>>> current page"
+)
+
+// Hash identifies a page body; the edit form carries the hash of the body it
+// started from so saves can detect that someone else changed the page meanwhile.
+// Line endings are normalised, so browsers sending CRLF don't cause false conflicts.
+func Hash(body string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(body, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Merge3 merges two edits (mine and theirs) of a common base text line by line.
+// Changes to different regions are combined; where both sides changed the same
+// region differently, both versions are kept between conflict markers.
+// It returns the merged text and the number of conflicting regions.
+func Merge3(base, mine, theirs string) (string, int) {
+	o, a, b := splitLines(base), splitLines(mine), splitLines(theirs)
+	matchA, matchB := matchLines(base, mine, len(o)), matchLines(base, theirs, len(o))
+
+	var out []string
+	conflicts := 0
+	i, ai, bi := 0, 0, 0
+	for i < len(o) || ai < len(a) || bi < len(b) {
+		// Line unchanged on both sides
+		if i < len(o) && matchA[i] == ai && matchB[i] == bi {
+			out = append(out, o[i])
+			i, ai, bi = i+1, ai+1, bi+1
+			continue
+		}
+
+		// Find the next base line both sides kept; everything before it is a changed chunk
+		j := i
+		for j < len(o) && (matchA[j] < 0 || matchB[j] < 0) {
+			j++
+		}
+		aEnd, bEnd := len(a), len(b)
+		if j < len(o) {
+			aEnd, bEnd = matchA[j], matchB[j]
+		}
+		baseChunk, mineChunk, theirChunk := o[i:j], a[ai:aEnd], b[bi:bEnd]
+
+		switch {
+		case equalLines(mineChunk, baseChunk):
+			out = append(out, theirChunk...)
+		case equalLines(theirChunk, baseChunk), equalLines(mineChunk, theirChunk):
+			out = append(out, mineChunk...)
+		default:
+			conflicts++
+			out = append(out, conflictStart)
+			out = append(out, mineChunk...)
+			out = append(out, conflictSep)
+			out = append(out, theirChunk...)
+			out = append(out, conflictEnd)
+		}
+		i, ai, bi = j, aEnd, bEnd
+	}
+
+	if len(out) == 0 {
+		return "", conflicts
+	}
+	return strings.Join(out, "\n") + "\n", conflicts
+}
+
+// matchLines maps every base line to its line index in the edited text
+// (-1 if it was deleted), using the same LCS alignment as Diff.
+func matchLines(base, edited string, n int) []int {
+	match := make([]int, n)
+	for i := range match {
+		match[i] = -1
+	}
+	for _, l := range Diff(base, edited) {
+		if l.Op == DiffEqual {
+			match[l.OldLine-1] = l.NewLine - 1
+		}
+	}
+	return match
+}
+
+// equalLines reports whether two line slices are identical.
+func equalLines(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
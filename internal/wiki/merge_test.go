@@ -0,0 +1,45 @@
+package wiki
+
+import (
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	const base = "a\nb\nc\nd\ne\n"
+
+	tests := []struct {
+		name               string
+		base, mine, theirs string
+		want               string
+		wantConflicts      int
+	}{
+		{"only mine changed", base, "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", 0},
+		{"only theirs changed", base, base, "a\nb\nc\nD\ne\n", "a\nb\nc\nD\ne\n", 0},
+		{"separate regions", base, "a\nB\nc\nd\ne\n", "a\nb\nc\nD\ne\n", "a\nB\nc\nD\ne\n", 0},
+		{"insert and delete", base, "x\na\nb\nc\nd\ne\n", "a\nb\nc\nd\n", "x\na\nb\nc\nd\n", 0},
+		{"same change both sides", base, "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		{
+			"overlapping change", base, "a\nmine\nc\nd\ne\n", "a\ntheirs\nc\nd\ne\n",
+			"a\n" + conflictStart + "\nmine\n" + conflictSep + "\ntheirs\n" + conflictEnd + "\nc\nd\ne\n", 1,
+		},
+		{"new page on both sides", "", "x\n", "y\n", conflictStart + "\nx\n" + conflictSep + "\ny\n" + conflictEnd + "\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge3(tt.base, tt.mine, tt.theirs)
+			if got != tt.want || conflicts != tt.wantConflicts {
+				t.Errorf("Merge3() = %q, %d; want %q, %d", got, conflicts, tt.want, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestHashIgnoresLineEndings(t *testing.T) {
+	if Hash("a\r\nb") != Hash("a\nb") {
+		t.Error("expected CRLF and LF bodies to hash the same")
+	}
+	if Hash("a") == Hash("b") {
+		t.Error("expected different bodies to hash differently")
+	}
+}
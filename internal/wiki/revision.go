@@ -0,0 +1,18 @@
+package wiki
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRevisionNotFound is returned when a page has no revision with the requested number.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is one saved version of a wiki page.
+type Revision struct {
+	Number    int       `json:"number"` // 1-based, increasing per page
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Summary   string    `json:"summary"`
+	Body      string    `json:"body"`
+}
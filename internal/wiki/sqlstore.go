@@ -0,0 +1,275 @@
+package wiki
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// SQLStore keeps wiki pages, revisions and attachments in the database
+// (tables wiki_page, wiki_revision and wiki_attachment, see schema.sql),
+// so several server replicas can share one wiki.
+type SQLStore struct {
+	db        *sql.DB
+	forUpdate string // the row-locking clause of SELECT in the database's dialect
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore creates a SQLStore using an open database connection.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	s := &SQLStore{db: db}
+	// SQLite has no row locks (a writing transaction locks the whole database)
+	if _, ok := db.Driver().(*mysql.MySQLDriver); ok {
+		s.forUpdate = " FOR UPDATE"
+	}
+	return s
+}
+
+// Close does nothing: the connection belongs to the caller.
+func (s *SQLStore) Close() error {
+	return nil
+}
+
+// Load returns the current body of a page.
+func (s *SQLStore) Load(title string) ([]byte, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	var body string
+	err := s.db.QueryRow("SELECT body FROM wiki_page WHERE title = ?", title).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, ErrPageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(body), nil
+}
+
+// Save writes a page and records it as a new revision, in one transaction.
+// The page row is locked first, so saves of the same page from several
+// replicas take turns instead of picking the same revision number.
+func (s *SQLStore) Save(title string, rev Revision) (Revision, error) {
+	return s.SaveIf(title, "", rev)
+}
+
+// SaveIf is Save, unless the page has changed since the edit based on base
+// began (see Store). The body is compared while the page row is locked.
+func (s *SQLStore) SaveIf(title, base string, rev Revision) (Revision, error) {
+	if err := ValidateTitle(title); err != nil {
+		return rev, err
+	}
+	if rev.Timestamp.IsZero() {
+		rev.Timestamp = time.Now().UTC()
+	}
+
+	saved, created, err := s.save(title, base, rev)
+	if err != nil && created {
+		// Another save may have created the page at the same time; it
+		// exists now, so a second try locks it like any other
+		saved, _, err = s.save(title, base, rev)
+	}
+	return saved, err
+}
+
+// save runs one attempt of Save. created reports whether it tried to create
+// the page row.
+func (s *SQLStore) save(title, base string, rev Revision) (saved Revision, created bool, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return rev, false, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow("SELECT body FROM wiki_page WHERE title = ?"+s.forUpdate, title).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return rev, false, err
+	}
+	if base != "" && Hash(current) != base {
+		return rev, false, ErrEditConflict
+	}
+	switch err {
+	case sql.ErrNoRows:
+		created = true
+		_, err = tx.Exec("INSERT INTO wiki_page (title, body, updated_at) VALUES (?, ?, ?)",
+			title, rev.Body, rev.Timestamp)
+	case nil:
+		_, err = tx.Exec("UPDATE wiki_page SET body = ?, updated_at = ? WHERE title = ?",
+			rev.Body, rev.Timestamp, title)
+	}
+	if err != nil {
+		return rev, created, err
+	}
+
+	var latest int
+	err = tx.QueryRow("SELECT COALESCE(MAX(number), 0) FROM wiki_revision WHERE title = ?", title).Scan(&latest)
+	if err != nil {
+		return rev, created, err
+	}
+	rev.Number = latest + 1
+
+	_, err = tx.Exec(
+		"INSERT INTO wiki_revision (title, number, author, created_at, summary, body) VALUES (?, ?, ?, ?, ?, ?)",
+		title, rev.Number, rev.Author, rev.Timestamp, rev.Summary, rev.Body)
+	if err != nil {
+		return rev, created, err
+	}
+	return rev, created, tx.Commit()
+}
+
+// List returns the titles of all pages, sorted.
+func (s *SQLStore) List() ([]string, error) {
+	rows, err := s.db.Query("SELECT title FROM wiki_page ORDER BY title")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
+
+// Revisions returns every revision of a page, oldest first (empty if none).
+func (s *SQLStore) Revisions(title string) ([]Revision, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(
+		"SELECT number, author, created_at, summary, body FROM wiki_revision WHERE title = ? ORDER BY number", title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revs := []Revision{}
+	for rows.Next() {
+		var rev Revision
+		if err := rows.Scan(&rev.Number, &rev.Author, &rev.Timestamp, &rev.Summary, &rev.Body); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+// Revision returns a single revision of a page by number.
+func (s *SQLStore) Revision(title string, number int) (Revision, error) {
+	if err := ValidateTitle(title); err != nil {
+		return Revision{}, err
+	}
+	rev := Revision{Number: number}
+	err := s.db.QueryRow(
+		"SELECT author, created_at, summary, body FROM wiki_revision WHERE title = ? AND number = ?",
+		title, number).Scan(&rev.Author, &rev.Timestamp, &rev.Summary, &rev.Body)
+	if err == sql.ErrNoRows {
+		return Revision{}, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+// SaveAttachment stores a file for an existing page, replacing any file with the same name.
+func (s *SQLStore) SaveAttachment(title, name string, content []byte) (Attachment, error) {
+	typ, err := validateUpload(title, name, content)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if _, err := s.Load(title); err != nil {
+		return Attachment{}, err
+	}
+	att := Attachment{
+		Name:        name,
+		Size:        int64(len(content)),
+		ContentType: typ.contentType,
+		Image:       typ.image,
+		Modified:    time.Now().UTC(),
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM wiki_attachment WHERE title = ? AND name = ?", title, name); err != nil {
+		return Attachment{}, err
+	}
+	_, err = tx.Exec("INSERT INTO wiki_attachment (title, name, content, modified) VALUES (?, ?, ?, ?)",
+		title, name, content, att.Modified)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return att, tx.Commit()
+}
+
+// Attachments lists a page's attachments, sorted by name (empty if none).
+func (s *SQLStore) Attachments(title string) ([]Attachment, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(
+		"SELECT name, LENGTH(content), modified FROM wiki_attachment WHERE title = ? ORDER BY name", title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var att Attachment
+		if err := rows.Scan(&att.Name, &att.Size, &att.Modified); err != nil {
+			return nil, err
+		}
+		typ, err := attachmentTypeOf(att.Name)
+		if err != nil {
+			continue
+		}
+		att.ContentType, att.Image = typ.contentType, typ.image
+		attachments = append(attachments, att)
+	}
+	return attachments, rows.Err()
+}
+
+// Attachment returns the content and details of one attachment.
+func (s *SQLStore) Attachment(title, name string) ([]byte, Attachment, error) {
+	typ, err := checkAttachment(title, name)
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	att := Attachment{Name: name, ContentType: typ.contentType, Image: typ.image}
+	var content []byte
+	err = s.db.QueryRow("SELECT content, modified FROM wiki_attachment WHERE title = ? AND name = ?",
+		title, name).Scan(&content, &att.Modified)
+	if err == sql.ErrNoRows {
+		return nil, Attachment{}, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, Attachment{}, err
+	}
+	att.Size = int64(len(content))
+	return content, att, nil
+}
+
+// DeleteAttachment removes an attachment.
+func (s *SQLStore) DeleteAttachment(title, name string) error {
+	if _, err := checkAttachment(title, name); err != nil {
+		return err
+	}
+	res, err := s.db.Exec("DELETE FROM wiki_attachment WHERE title = ? AND name = ?", title, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
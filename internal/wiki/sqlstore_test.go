@@ -0,0 +1,160 @@
+package wiki
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestSQLStore creates a SQLStore on an in-memory SQLite database with the
+// same tables as schema.sql (written in SQLite's dialect).
+func newTestSQLStore(t *testing.T) *SQLStore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1) // every connection to :memory: is a separate database
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE wiki_page (title TEXT PRIMARY KEY, body TEXT NOT NULL, updated_at DATETIME NOT NULL);
+		CREATE TABLE wiki_revision (
+			title TEXT NOT NULL, number INTEGER NOT NULL, author TEXT NOT NULL,
+			created_at DATETIME NOT NULL, summary TEXT NOT NULL, body TEXT NOT NULL,
+			PRIMARY KEY (title, number)
+		);
+		CREATE TABLE wiki_attachment (
+			title TEXT NOT NULL, name TEXT NOT NULL, content BLOB NOT NULL, modified DATETIME NOT NULL,
+			PRIMARY KEY (title, name)
+		);`)
+	if err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+	return NewSQLStore(db)
+}
+
+func TestSQLStore(t *testing.T) {
+	s := newTestSQLStore(t)
+
+	if _, err := s.Load("Guides/Setup"); err != ErrPageNotFound {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+	if _, err := s.Save("../escape", Revision{Body: "x"}); err != ErrInvalidTitle {
+		t.Errorf("expected ErrInvalidTitle, got %v", err)
+	}
+
+	s.Save("Guides/Setup", Revision{Author: "alice", Body: "one"})
+	rev, err := s.Save("Guides/Setup", Revision{Author: "bob", Summary: "fix", Body: "two"})
+	if err != nil || rev.Number != 2 {
+		t.Fatalf("expected revision 2, got %+v, %v", rev, err)
+	}
+
+	body, err := s.Load("Guides/Setup")
+	if err != nil || string(body) != "two" {
+		t.Errorf("unexpected body %q, %v", body, err)
+	}
+	first, err := s.Revision("Guides/Setup", 1)
+	if err != nil || first.Author != "alice" || first.Body != "one" {
+		t.Errorf("unexpected revision 1: %+v, %v", first, err)
+	}
+	if _, err := s.Revision("Guides/Setup", 3); err != ErrRevisionNotFound {
+		t.Errorf("expected ErrRevisionNotFound, got %v", err)
+	}
+	if titles, _ := s.List(); len(titles) != 1 || titles[0] != "Guides/Setup" {
+		t.Errorf("unexpected page list: %v", titles)
+	}
+
+	if _, err := s.SaveAttachment("Guides/Setup", "diagram.png", pngHeader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.SaveAttachment("Missing", "diagram.png", pngHeader); err != ErrPageNotFound {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+	list, err := s.Attachments("Guides/Setup")
+	if err != nil || len(list) != 1 || list[0].Size != int64(len(pngHeader)) || !list[0].Image {
+		t.Errorf("unexpected attachments: %+v, %v", list, err)
+	}
+	if err := s.DeleteAttachment("Guides/Setup", "diagram.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.DeleteAttachment("Guides/Setup", "diagram.png"); err != ErrAttachmentNotFound {
+		t.Errorf("expected ErrAttachmentNotFound, got %v", err)
+	}
+}
+
+func TestSaveIf(t *testing.T) {
+	fileStore, _ := newTestStore(t)
+	for name, s := range map[string]Store{"file": fileStore, "sql": newTestSQLStore(t)} {
+		// A new page is edited from an empty body
+		if _, err := s.SaveIf("Page", Hash(""), Revision{Body: "one"}); err != nil {
+			t.Fatalf("%s: unexpected error creating the page: %v", name, err)
+		}
+		if _, err := s.SaveIf("Page", Hash(""), Revision{Body: "also new"}); err != ErrEditConflict {
+			t.Errorf("%s: expected ErrEditConflict for a second creation, got %v", name, err)
+		}
+		if rev, err := s.SaveIf("Page", Hash("one"), Revision{Body: "two"}); err != nil || rev.Number != 2 {
+			t.Errorf("%s: expected revision 2, got %+v, %v", name, rev, err)
+		}
+		if _, err := s.SaveIf("Page", Hash("one"), Revision{Body: "stale"}); err != ErrEditConflict {
+			t.Errorf("%s: expected ErrEditConflict for a stale base, got %v", name, err)
+		}
+		if body, _ := s.Load("Page"); string(body) != "two" {
+			t.Errorf("%s: a conflicting save was written: %q", name, body)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	src, dir := newTestStore(t)
+	dst := newTestSQLStore(t)
+
+	// A page file from before revision history, and a page with history and an attachment
+	os.WriteFile(filepath.Join(dir, "FrontPage.txt"), []byte("Welcome to [Page1]"), 0600)
+	when := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	src.Save("Page1", Revision{Author: "alice", Timestamp: when, Body: "first"})
+	src.Save("Page1", Revision{Author: "bob", Timestamp: when.Add(time.Hour), Summary: "more", Body: "second"})
+	src.SaveAttachment("Page1", "diagram.png", pngHeader)
+
+	// Pages edited in the destination are left alone
+	dst.Save("Existing", Revision{Body: "keep me"})
+	os.WriteFile(filepath.Join(dir, "Existing.txt"), []byte("overwrite?"), 0600)
+
+	// An earlier run was interrupted after the first revision of Page1
+	dst.Save("Page1", Revision{Author: "alice", Timestamp: when, Body: "first"})
+
+	result, err := Import(dst, src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Pages != 2 || result.Revisions != 2 || result.Attachments != 1 ||
+		len(result.Skipped) != 1 || result.Skipped[0] != "Existing" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	revs, err := dst.Revisions("Page1")
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("expected 2 revisions, got %+v, %v", revs, err)
+	}
+	if revs[1].Author != "bob" || revs[1].Summary != "more" || !revs[1].Timestamp.Equal(when.Add(time.Hour)) {
+		t.Errorf("history not preserved: %+v", revs[1])
+	}
+	if body, _ := dst.Load("FrontPage"); string(body) != "Welcome to [Page1]" {
+		t.Errorf("unexpected FrontPage body %q", body)
+	}
+	if body, _ := dst.Load("Existing"); string(body) != "keep me" {
+		t.Errorf("existing page was overwritten: %q", body)
+	}
+	if _, _, err := dst.Attachment("Page1", "diagram.png"); err != nil {
+		t.Errorf("attachment not imported: %v", err)
+	}
+
+	// Running it again finds nothing left to do
+	result, err = Import(dst, src)
+	if err != nil || result.Pages != 0 || result.Revisions != 0 || len(result.Skipped) != 1 {
+		t.Errorf("expected nothing imported again, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,265 @@
+package wiki
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPageNotFound is returned when a page doesn't exist.
+var ErrPageNotFound = errors.New("page not found")
+
+// ErrEditConflict is returned by SaveIf when the page changed since the edit began.
+var ErrEditConflict = errors.New("page changed since the edit began")
+
+// pageExt is the file extension of page files.
+const pageExt = ".txt"
+
+// historyDir holds revision files inside the store root. Titles can't start
+// with '.', so it never collides with a page namespace.
+const historyDir = ".history"
+
+// Store is where wiki pages, their revision history and attachments are kept.
+// FileStore keeps them in a directory and SQLStore in the database; which one
+// the server uses is chosen by configuration.
+type Store interface {
+	// Load returns the current body of a page, or ErrPageNotFound.
+	Load(title string) ([]byte, error)
+	// Save writes a page and records rev as its next revision. A non-zero
+	// rev.Timestamp is kept (used when importing history).
+	Save(title string, rev Revision) (Revision, error)
+	// SaveIf is Save for an edit that began when the page body hashed to
+	// base (see Hash; a missing page counts as empty). If the page has
+	// changed since, nothing is written and ErrEditConflict is returned. The
+	// check and the write are atomic. An empty base skips the check.
+	SaveIf(title, base string, rev Revision) (Revision, error)
+	// List returns the titles of all pages, sorted.
+	List() ([]string, error)
+	// Revisions returns every revision of a page, oldest first.
+	Revisions(title string) ([]Revision, error)
+	// Revision returns one revision by number, or ErrRevisionNotFound.
+	Revision(title string, number int) (Revision, error)
+
+	SaveAttachment(title, name string, content []byte) (Attachment, error)
+	Attachments(title string) ([]Attachment, error)
+	Attachment(title, name string) ([]byte, Attachment, error)
+	DeleteAttachment(title, name string) error
+
+	Close() error
+}
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore keeps wiki pages as <Title>.txt files under a directory.
+// All file access goes through an os.Root, so no title can resolve to a
+// path outside that directory (including via symlinks).
+type FileStore struct {
+	root *os.Root
+	mu   sync.Mutex // serialises page writes and history read-modify-write
+}
+
+// NewFileStore opens (and creates if needed) the directory that holds the pages.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{root: root}, nil
+}
+
+// Close releases the underlying directory handle.
+func (s *FileStore) Close() error {
+	return s.root.Close()
+}
+
+// Load returns the current body of a page.
+func (s *FileStore) Load(title string) ([]byte, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	body, err := s.readFile(title + pageExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrPageNotFound
+	}
+	return body, err
+}
+
+// Save writes a page and records it as a new revision (rev.Body is the page body).
+// Pages that existed before revision history are first recorded as revision 1.
+func (s *FileStore) Save(title string, rev Revision) (Revision, error) {
+	return s.SaveIf(title, "", rev)
+}
+
+// SaveIf is Save, unless the page has changed since the edit based on base
+// began (see Store).
+func (s *FileStore) SaveIf(title, base string, rev Revision) (Revision, error) {
+	if err := ValidateTitle(title); err != nil {
+		return rev, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if base != "" {
+		current, err := s.readFile(title + pageExt)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return rev, err
+		}
+		if Hash(string(current)) != base {
+			return rev, ErrEditConflict
+		}
+	}
+
+	revs, err := s.revisions(title)
+	if err != nil {
+		return rev, err
+	}
+	if err := s.writeFile(title+pageExt, []byte(rev.Body)); err != nil {
+		return rev, err
+	}
+
+	rev.Number = len(revs) + 1
+	if rev.Timestamp.IsZero() {
+		rev.Timestamp = time.Now().UTC()
+	}
+	revs = append(revs, rev)
+
+	content, err := json.MarshalIndent(revs, "", "  ")
+	if err != nil {
+		return rev, err
+	}
+	return rev, s.writeFile(path.Join(historyDir, title+".json"), content)
+}
+
+// List returns the titles of all pages, sorted, including namespaced ones.
+func (s *FileStore) List() ([]string, error) {
+	var titles []string
+	err := fs.WalkDir(s.root.FS(), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (p == historyDir || isAttachmentDir(p)) {
+			return fs.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(p, pageExt) {
+			return nil
+		}
+		title := strings.TrimSuffix(p, pageExt)
+		if ValidateTitle(title) == nil { // skip stray files that aren't valid pages
+			titles = append(titles, title)
+		}
+		return nil
+	})
+	sort.Strings(titles)
+	return titles, err
+}
+
+// Revisions returns every revision of a page, oldest first (empty if none).
+func (s *FileStore) Revisions(title string) ([]Revision, error) {
+	if err := ValidateTitle(title); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revisions(title)
+}
+
+// Revision returns a single revision of a page by number.
+func (s *FileStore) Revision(title string, number int) (Revision, error) {
+	revs, err := s.Revisions(title)
+	if err != nil {
+		return Revision{}, err
+	}
+	if number < 1 || number > len(revs) {
+		return Revision{}, ErrRevisionNotFound
+	}
+	return revs[number-1], nil
+}
+
+// revisions reads a page's history, seeding it from the current page file
+// for pages created before history existed. Caller must hold s.mu.
+func (s *FileStore) revisions(title string) ([]Revision, error) {
+	content, err := s.readFile(path.Join(historyDir, title+".json"))
+	if err == nil {
+		var revs []Revision
+		if err := json.Unmarshal(content, &revs); err != nil {
+			return nil, err
+		}
+		return revs, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// No history yet: treat an existing page file as revision 1
+	info, err := s.root.Stat(title + pageExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.readFile(title + pageExt)
+	if err != nil {
+		return nil, err
+	}
+	return []Revision{{
+		Number:    1,
+		Author:    "unknown",
+		Timestamp: info.ModTime().UTC(),
+		Summary:   "Imported existing page",
+		Body:      string(body),
+	}}, nil
+}
+
+// readFile reads a file relative to the store root.
+func (s *FileStore) readFile(name string) ([]byte, error) {
+	f, err := s.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFile writes a file relative to the store root (owner-only permissions),
+// creating parent namespace directories as needed.
+func (s *FileStore) writeFile(name string, content []byte) error {
+	if err := s.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+	f, err := s.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mkdirAll creates every directory in dir (a slash-separated path) inside the root.
+func (s *FileStore) mkdirAll(dir string) error {
+	if dir == "." {
+		return nil
+	}
+	current := ""
+	for _, seg := range strings.Split(dir, "/") {
+		current = path.Join(current, seg)
+		if err := s.root.Mkdir(current, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package wiki
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*FileStore, string) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, dir
+}
+
+func TestValidateTitle(t *testing.T) {
+	valid := []string{"FrontPage", "Page1", "page 1", "Guides/Setup", "a/b/c/d", "Under_score-dash"}
+	invalid := []string{
+		"", "..", "../etc/passwd", "..%2F..%2Fetc%2Fpasswd", "/abs", "a//b", "a/", ".hidden",
+		"page.txt", "a/../b", "trailing ", " leading", "a/b/c/d/e", "back\\slash", "nul", "Com1",
+		strings.Repeat("x", 65),
+	}
+	for _, title := range valid {
+		if err := ValidateTitle(title); err != nil {
+			t.Errorf("ValidateTitle(%q) = %v, want nil", title, err)
+		}
+	}
+	for _, title := range invalid {
+		if err := ValidateTitle(title); err == nil {
+			t.Errorf("ValidateTitle(%q) = nil, want error", title)
+		}
+	}
+}
+
+func TestFileStoreSaveAndHistory(t *testing.T) {
+	s, dir := newTestStore(t)
+
+	// A page written before history existed becomes revision 1
+	os.WriteFile(filepath.Join(dir, "Legacy.txt"), []byte("old"), 0600)
+
+	rev, err := s.Save("Legacy", Revision{Author: "alice", Summary: "update", Body: "new"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rev.Number != 2 {
+		t.Errorf("expected revision 2, got %d", rev.Number)
+	}
+
+	first, err := s.Revision("Legacy", 1)
+	if err != nil || first.Body != "old" {
+		t.Errorf("expected legacy content as revision 1, got %+v, %v", first, err)
+	}
+	if _, err := s.Revision("Legacy", 3); err != ErrRevisionNotFound {
+		t.Errorf("expected ErrRevisionNotFound, got %v", err)
+	}
+
+	// Namespaced pages get their own directories
+	if _, err := s.Save("Guides/Setup", Revision{Body: "steps"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := s.Load("Guides/Setup")
+	if err != nil || string(body) != "steps" {
+		t.Errorf("unexpected namespaced page: %q, %v", body, err)
+	}
+
+	titles, _ := s.List()
+	if strings.Join(titles, ",") != "Guides/Setup,Legacy" {
+		t.Errorf("unexpected titles: %v", titles)
+	}
+
+	if _, err := s.Load("Missing"); err != ErrPageNotFound {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestFileStoreRejectsEscapingSymlink(t *testing.T) {
+	s, dir := newTestStore(t)
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "Link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	// The title is valid, but os.Root refuses to follow the link out of the store
+	if _, err := s.Save("Link/Page", Revision{Body: "x"}); err == nil {
+		t.Fatal("expected save through an escaping symlink to fail")
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("file was written outside the store: %v", entries)
+	}
+}
+
+func FuzzValidateTitle(f *testing.F) {
+	for _, seed := range []string{"FrontPage", "Guides/Setup", "../x", "a/../../b", "..\\..\\x", "%2e%2e/x", "a\x00b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, title string) {
+		if ValidateTitle(title) != nil {
+			return
+		}
+		// Anything accepted must be a local, clean, slash-separated path
+		name := title + pageExt
+		if !filepath.IsLocal(name) || filepath.Clean(name) != name {
+			t.Fatalf("accepted title %q resolves to non-local path", title)
+		}
+		for _, seg := range strings.Split(title, "/") {
+			if seg == "" || seg == "." || seg == ".." || strings.ContainsAny(seg, ".\\\x00") {
+				t.Fatalf("accepted title %q has unsafe segment %q", title, seg)
+			}
+		}
+	})
+}
+
+func FuzzFileStoreSave(f *testing.F) {
+	for _, seed := range []string{"Page", "NS/Page", "../escape", "/etc/passwd", "a/./b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, title string) {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "store")
+		s, err := NewFileStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		s.Save(title, Revision{Body: "x"})
+
+		// Whatever the title, nothing may be created next to the store directory
+		entries, _ := os.ReadDir(parent)
+		if len(entries) != 1 || entries[0].Name() != "store" {
+			t.Fatalf("title %q wrote outside the store: %v", title, entries)
+		}
+	})
+}
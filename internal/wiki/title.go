@@ -0,0 +1,50 @@
+package wiki
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// MaxTitleLength is the maximum length of a full page title, including namespaces.
+const MaxTitleLength = 200
+
+// maxNamespaceDepth limits how deeply pages can be nested ("A/B/C/Page" = 4 segments).
+const maxNamespaceDepth = 4
+
+// ErrInvalidTitle is returned for titles that don't match the allowed pattern.
+var ErrInvalidTitle = errors.New("invalid page title")
+
+// segmentPattern is the strict allow-list for one title segment: it must start with
+// a letter or digit and may contain letters, digits, spaces, '_' and '-'.
+// Dots are never allowed, which rules out "..", hidden files and extension tricks.
+var segmentPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]{0,63}$`)
+
+// reservedNames are device names that can't be used as file names on Windows.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"LPT1": true, "LPT2": true, "LPT3": true,
+}
+
+// ValidateTitle checks a page title. Namespaces are separated by '/',
+// e.g. "Guides/Setup"; each segment must match segmentPattern.
+func ValidateTitle(title string) error {
+	if title == "" || len(title) > MaxTitleLength {
+		return ErrInvalidTitle
+	}
+
+	segments := strings.Split(title, "/")
+	if len(segments) > maxNamespaceDepth {
+		return ErrInvalidTitle
+	}
+	for _, seg := range segments {
+		if !segmentPattern.MatchString(seg) || strings.HasSuffix(seg, " ") {
+			return ErrInvalidTitle
+		}
+		if reservedNames[strings.ToUpper(seg)] {
+			return ErrInvalidTitle
+		}
+	}
+	return nil
+}
@@ -5,21 +5,34 @@ CREATE TABLE IF NOT EXISTS users (
     created_at DATETIME
 );
 
+CREATE TABLE IF NOT EXISTS artist (
+    id   INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
+INSERT IGNORE INTO artist (id, name)
+VALUES
+    (1, 'John Coltrane'),
+    (2, 'Gerry Mulligan'),
+    (3, 'Sarah Vaughan');
+
 CREATE TABLE IF NOT EXISTS album (
-    id       INT AUTO_INCREMENT NOT NULL,
-    title    VARCHAR(128) NOT NULL,
-    artist   VARCHAR(255) NOT NULL,
-    price    DECIMAL(5,2) NOT NULL,
-    quantity INT NOT NULL DEFAULT 0,
-    PRIMARY KEY (`id`)
+    id        INT AUTO_INCREMENT NOT NULL,
+    title     VARCHAR(128) NOT NULL,
+    artist_id INT NOT NULL,
+    price     DECIMAL(5,2) NOT NULL,
+    quantity  INT NOT NULL DEFAULT 0,
+    version   INT NOT NULL DEFAULT 1,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (artist_id) REFERENCES artist(id)
 );
 
-INSERT IGNORE INTO album (id, title, artist, price, quantity)
+INSERT IGNORE INTO album (id, title, artist_id, price, quantity)
 VALUES
-    (1, 'Blue Train', 'John Coltrane', 56.99, 10),
-    (2, 'Giant Steps', 'John Coltrane', 63.99, 8),
-    (3, 'Jeru', 'Gerry Mulligan', 17.99, 12),
-    (4, 'Sarah Vaughan', 'Sarah Vaughan', 34.98, 5);
+    (1, 'Blue Train', 1, 56.99, 10),
+    (2, 'Giant Steps', 1, 63.99, 8),
+    (3, 'Jeru', 2, 17.99, 12),
+    (4, 'Sarah Vaughan', 3, 34.98, 5);
 
 CREATE TABLE IF NOT EXISTS customer (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -45,3 +58,42 @@ CREATE TABLE IF NOT EXISTS album_order (
     FOREIGN KEY (album_id) REFERENCES album(id),
     FOREIGN KEY (cust_id) REFERENCES customer(id)
 );
+
+-- Wiki storage (used when WIKI_STORE=sql). Titles are case-sensitive, like page files.
+CREATE TABLE IF NOT EXISTS wiki_page (
+    title      VARCHAR(200) COLLATE utf8mb4_bin NOT NULL,
+    body       MEDIUMTEXT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (title)
+);
+
+CREATE TABLE IF NOT EXISTS wiki_revision (
+    title      VARCHAR(200) COLLATE utf8mb4_bin NOT NULL,
+    number     INT NOT NULL,
+    author     VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    summary    TEXT NOT NULL,
+    body       MEDIUMTEXT NOT NULL,
+    PRIMARY KEY (title, number)
+);
+
+CREATE TABLE IF NOT EXISTS wiki_attachment (
+    title    VARCHAR(200) COLLATE utf8mb4_bin NOT NULL,
+    name     VARCHAR(128) COLLATE utf8mb4_bin NOT NULL,
+    content  MEDIUMBLOB NOT NULL,
+    modified DATETIME NOT NULL,
+    PRIMARY KEY (title, name)
+);
+
+-- Chat messages broadcast to WebSocket rooms, replayed to clients that join.
+CREATE TABLE IF NOT EXISTS messages (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    room       VARCHAR(64) NOT NULL,
+    client_id  VARCHAR(32) NOT NULL,
+    user_id    INT NOT NULL,
+    username   VARCHAR(255) NOT NULL,
+    data       MEDIUMTEXT NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_messages_room (room, id),
+    INDEX idx_messages_created_at (created_at)
+);
@@ -0,0 +1,176 @@
+// Pathfinding playground: edits a grid on a canvas and animates the search
+// streamed from GET /pathfinder/stream (server-sent events).
+
+const FREE = 0, WALL = 1;
+const COLORS = { free: '#fff', wall: '#333', weight: '#d9b38c', visited: '#9ecbf0', path: '#f1c40f', start: '#2ecc71', end: '#e74c3c' };
+
+const canvas = document.getElementById('grid');
+const ctx = canvas.getContext('2d');
+const status = document.getElementById('status');
+const $ = id => document.getElementById(id);
+
+let grid = [];          // grid[row][col]: 0 free, 1 wall, 2-9 weighted
+let start = { x: 0, y: 0 };
+let end = { x: 0, y: 0 };
+let visited = new Set(); // "x,y" keys of expanded cells
+let path = new Set();
+let source = null;       // the running EventSource
+let cell = 20;           // cell size in pixels
+
+const key = p => `${p.x},${p.y}`;
+
+// newGrid replaces the grid with an empty one and puts start/end in opposite corners
+function newGrid(rows, cols) {
+    grid = Array.from({ length: rows }, () => new Array(cols).fill(FREE));
+    start = { x: 0, y: 0 };
+    end = { x: rows - 1, y: cols - 1 };
+    resetSearch();
+}
+
+function resetSearch() {
+    visited = new Set();
+    path = new Set();
+    cell = Math.max(6, Math.min(28, Math.floor(840 / grid[0].length)));
+    canvas.width = grid[0].length * cell;
+    canvas.height = grid.length * cell;
+    draw();
+}
+
+function colorOf(x, y) {
+    const k = key({ x, y });
+    if (k === key(start)) return COLORS.start;
+    if (k === key(end)) return COLORS.end;
+    if (path.has(k)) return COLORS.path;
+    if (grid[x][y] === WALL) return COLORS.wall;
+    if (visited.has(k)) return COLORS.visited;
+    if (grid[x][y] > WALL) return COLORS.weight;
+    return COLORS.free;
+}
+
+function drawCell(x, y) {
+    ctx.fillStyle = colorOf(x, y);
+    ctx.fillRect(y * cell, x * cell, cell, cell);
+    ctx.strokeStyle = '#ddd';
+    ctx.strokeRect(y * cell + 0.5, x * cell + 0.5, cell - 1, cell - 1);
+    if (grid[x][y] > WALL && cell >= 12) {
+        ctx.fillStyle = '#333';
+        ctx.font = `${Math.floor(cell * 0.6)}px sans-serif`;
+        ctx.textAlign = 'center';
+        ctx.textBaseline = 'middle';
+        ctx.fillText(grid[x][y], y * cell + cell / 2, x * cell + cell / 2);
+    }
+}
+
+function draw() {
+    for (let x = 0; x < grid.length; x++) {
+        for (let y = 0; y < grid[0].length; y++) {
+            drawCell(x, y);
+        }
+    }
+}
+
+// toASCII draws the grid in the notation read by the server (. # 2-9 S E)
+function toASCII() {
+    return grid.map((row, x) => row.map((v, y) => {
+        if (x === start.x && y === start.y) return 'S';
+        if (x === end.x && y === end.y) return 'E';
+        return v === FREE ? '.' : v === WALL ? '#' : String(v);
+    }).join('')).join('\n');
+}
+
+// ---- Editing ----
+
+let painting = false;
+
+function paint(e) {
+    const rect = canvas.getBoundingClientRect();
+    const x = Math.floor((e.clientY - rect.top) / cell);
+    const y = Math.floor((e.clientX - rect.left) / cell);
+    if (source || x < 0 || y < 0 || x >= grid.length || y >= grid[0].length) return;
+
+    const tool = document.querySelector('input[name=tool]:checked').value;
+    const old = [start, end];
+    if (tool === 'start' || tool === 'end') {
+        grid[x][y] = FREE;
+        if (tool === 'start') start = { x, y }; else end = { x, y };
+    } else if (key({ x, y }) !== key(start) && key({ x, y }) !== key(end)) {
+        grid[x][y] = tool === 'wall' ? WALL : tool === 'weight' ? Number($('weight').value) : FREE;
+    }
+    if (visited.size || path.size) {
+        resetSearch(); // the old search no longer matches the grid
+    } else {
+        old.forEach(p => drawCell(p.x, p.y));
+        drawCell(x, y);
+    }
+}
+
+canvas.addEventListener('mousedown', e => { painting = true; paint(e); });
+canvas.addEventListener('mousemove', e => { if (painting) paint(e); });
+window.addEventListener('mouseup', () => { painting = false; });
+
+$('resize').addEventListener('click', () => {
+    newGrid(Number($('rows').value), Number($('cols').value));
+    status.textContent = 'Ready.';
+});
+
+$('generate').addEventListener('click', async () => {
+    const params = new URLSearchParams({ type: $('maze').value, rows: $('rows').value, cols: $('cols').value });
+    const res = await fetch('/pathfinder/generate?' + params);
+    if (!res.ok) {
+        const problem = await res.json(); // problem details
+        status.textContent = 'Error: ' + (problem.detail || problem.title);
+        return;
+    }
+    const maze = await res.json();
+    grid = maze.grid;
+    start = maze.start;
+    end = maze.end;
+    resetSearch();
+    status.textContent = `Generated a ${maze.type} grid (seed ${maze.seed}).`;
+});
+
+// ---- Searching ----
+
+function finish(message) {
+    if (source) source.close();
+    source = null;
+    $('run').disabled = false;
+    $('stop').disabled = true;
+    status.textContent = message;
+}
+
+$('run').addEventListener('click', () => {
+    resetSearch();
+    const params = new URLSearchParams({
+        grid: toASCII(),
+        algorithm: $('algorithm').value,
+        diagonal: $('diagonal').checked,
+        corners: $('corners').value,
+        delay: $('delay').value,
+    });
+    $('run').disabled = true;
+    $('stop').disabled = false;
+    status.textContent = 'Searching...';
+
+    source = new EventSource('/pathfinder/stream?' + params);
+    source.addEventListener('visit', e => {
+        const p = JSON.parse(e.data);
+        visited.add(key(p));
+        drawCell(p.x, p.y);
+        status.textContent = `Searching... ${visited.size} nodes expanded`;
+    });
+    source.addEventListener('done', e => {
+        const res = JSON.parse(e.data);
+        res.path.forEach(p => { path.add(key(p)); drawCell(p.x, p.y); });
+        finish(res.path_length < 0
+            ? `${res.algorithm}: no path (${res.nodes_expanded} nodes expanded)`
+            : `${res.algorithm}: ${res.path_length} moves, cost ${res.cost.toFixed(2)}, ${res.nodes_expanded} nodes expanded`);
+    });
+    source.addEventListener('fail', e => finish('Error: ' + e.data));
+    source.onerror = () => { if (source) finish('Connection lost.'); };
+});
+
+$('stop').addEventListener('click', () => finish('Stopped.'));
+$('clear').addEventListener('click', () => { resetSearch(); status.textContent = 'Ready.'; });
+
+newGrid(15, 25);
@@ -98,6 +98,8 @@ bindForm('customer-name-form', '/customer-name', 'GET');
 bindForm('json-encode-form', '/json/encode', 'POST');
 bindForm('json-decode-form', '/json/decode', 'POST');
 bindForm('pathfinder-form', '/pathfinder', 'GET');
+bindForm('pathfinder-solve-form', '/pathfinder', 'POST');
+bindForm('pathfinder-generate-form', '/pathfinder/generate', 'GET');
 bindForm('runtime-errors-form', '/runtime-errors', 'GET');
 
 // Form validation: stops empty required fields from submitting
@@ -115,3 +117,29 @@ document.querySelectorAll("form").forEach(form => {
         if (invalid) e.preventDefault();
     });
 });
+
+// Watch stock: subscribe to the "albums" topic over /ws and log every stock change
+const watchStockBtn = document.getElementById('watch-stock-btn');
+if (watchStockBtn) {
+    watchStockBtn.addEventListener('click', () => {
+        const log = document.getElementById('watch-stock-log');
+        const protocol = location.protocol === 'https:' ? 'wss://' : 'ws://';
+        const ws = new WebSocket(protocol + location.host + '/ws');
+        watchStockBtn.disabled = true;
+        log.textContent = 'Connecting...';
+
+        ws.onopen = () => ws.send(JSON.stringify({ type: 'subscribe', room: 'albums' }));
+        ws.onmessage = e => {
+            const msg = JSON.parse(e.data);
+            if (msg.type === 'subscribe') {
+                log.textContent = 'Watching stock (place an order above)...';
+            } else if (msg.type === 'event' && msg.event === 'stock.changed') {
+                log.textContent += `\nAlbum ${msg.data.album_id}: ${msg.data.quantity} in stock (version ${msg.data.version})`;
+            }
+        };
+        ws.onclose = () => {
+            log.textContent += '\nDisconnected (log in first: /ws requires a session).';
+            watchStockBtn.disabled = false;
+        };
+    });
+}